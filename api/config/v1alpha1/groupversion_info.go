@@ -0,0 +1,32 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1alpha1 contains the configuration file types of the operator
+// These are read from a file (not served), so there is no groupName (or CRD).
+//+kubebuilder:object:generate=true
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	// GroupVersion is the apiVersion of the configuration file
+	GroupVersion = schema.GroupVersion{Group: "config.my.domain", Version: "v1alpha1"}
+)
+
+// Kind of the configuration file
+const OperatorConfigKind = "OperatorConfig"
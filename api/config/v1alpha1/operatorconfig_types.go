@@ -0,0 +1,181 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cfg "sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
+)
+
+//+kubebuilder:object:root=true
+
+// OperatorConfig is the configuration file of the lolcow operator
+// Anything set here can be overridden by the matching command line flag.
+type OperatorConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// ControllerManagerConfigurationSpec configures the manager: metrics,
+	// health probes, webhook, leader election, cache and sync period
+	cfg.ControllerManagerConfigurationSpec `json:",inline"`
+
+	// Controllers to run, e.g., ["*", "-foo"] for all but foo
+	// Defaults to all of them ("*").
+	// +optional
+	Controllers []string `json:"controllers,omitempty"`
+
+	// Lolcow configures the lolcow controller
+	// +optional
+	Lolcow LolcowConfig `json:"lolcow,omitempty"`
+
+	// FeatureGates turn features on or off by name
+	// +optional
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+
+	// WebhookCertificates has the manager make its own webhook certificates,
+	// for clusters without cert-manager
+	// +optional
+	WebhookCertificates WebhookCertificatesConfig `json:"webhookCertificates,omitempty"`
+
+	// SecureMetrics has the manager serve the metrics over HTTPS, to those
+	// allowed to read them, instead of the kube-rbac-proxy sidecar
+	// +optional
+	SecureMetrics SecureMetricsConfig `json:"secureMetrics,omitempty"`
+
+	// PermissionCheck is what to do when the manager misses a permission
+	// at startup: fail, degrade (log them, and turn off the feature gates
+	// that need them) or off (don't check). Defaults to degrade.
+	// +optional
+	PermissionCheck string `json:"permissionCheck,omitempty"`
+}
+
+// SecureMetricsConfig configures the HTTPS metrics server
+type SecureMetricsConfig struct {
+
+	// Enabled serves metrics.bindAddress over HTTPS. Requests need a bearer
+	// token (a TokenReview) whose user may get /metrics (a SubjectAccessReview).
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// CertDir has the serving certificate, tls.crt and tls.key
+	// Without one, a self signed certificate is made at startup.
+	// +optional
+	CertDir string `json:"certDir,omitempty"`
+}
+
+// WebhookCertificatesConfig configures the webhook certificates the manager
+// makes, rotates and injects itself
+type WebhookCertificatesConfig struct {
+
+	// SelfManaged turns it on. Otherwise the certificate comes from cert-manager.
+	// +optional
+	SelfManaged bool `json:"selfManaged,omitempty"`
+
+	// SecretName keeps the CA and the serving certificate, in the manager's namespace
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// ServiceName is the webhook Service, in the manager's namespace
+	// +optional
+	ServiceName string `json:"serviceName,omitempty"`
+
+	// MutatingWebhookConfigurations to inject the CA into
+	// +optional
+	MutatingWebhookConfigurations []string `json:"mutatingWebhookConfigurations,omitempty"`
+
+	// ValidatingWebhookConfigurations to inject the CA into
+	// +optional
+	ValidatingWebhookConfigurations []string `json:"validatingWebhookConfigurations,omitempty"`
+
+	// CustomResourceDefinitions to inject the CA into, if they have a conversion webhook
+	// +optional
+	CustomResourceDefinitions []string `json:"customResourceDefinitions,omitempty"`
+
+	// Validity of the serving certificate, renewed when a third is left
+	// +optional
+	Validity metav1.Duration `json:"validity,omitempty"`
+}
+
+// LolcowConfig configures the lolcow controller and what it creates
+type LolcowConfig struct {
+
+	// MaxConcurrentReconciles is how many lolcows can be reconciled at once
+	// +optional
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+
+	// RateLimiter sets how quickly failed lolcows are retried
+	// +optional
+	RateLimiter RateLimiterConfig `json:"rateLimiter,omitempty"`
+
+	// StuckQueueTimeout fails the liveness probe when lolcows are pending,
+	// and no reconcile finished for this long. 0 turns the check off.
+	// +optional
+	StuckQueueTimeout metav1.Duration `json:"stuckQueueTimeout,omitempty"`
+
+	// WatchNamespaces limits the lolcows watched to these namespaces
+	// +optional
+	WatchNamespaces []string `json:"watchNamespaces,omitempty"`
+
+	// DefaultImage is the image that serves the greeting
+	// +optional
+	DefaultImage string `json:"defaultImage,omitempty"`
+
+	// DefaultGreeting is used for lolcows that don't have a greeting
+	// +optional
+	DefaultGreeting string `json:"defaultGreeting,omitempty"`
+
+	// DefaultServiceType is the type of the lolcow Services
+	// One of LoadBalancer, NodePort or ClusterIP
+	// +optional
+	DefaultServiceType corev1.ServiceType `json:"defaultServiceType,omitempty"`
+
+	// PropagateLabelPrefixes are the prefixes of Lolcow labels copied to its children
+	// +optional
+	PropagateLabelPrefixes []string `json:"propagateLabelPrefixes,omitempty"`
+
+	// PropagateAnnotationPrefixes are the prefixes of Lolcow annotations copied to its children
+	// +optional
+	PropagateAnnotationPrefixes []string `json:"propagateAnnotationPrefixes,omitempty"`
+}
+
+// RateLimiterConfig is the workqueue rate limiter of the lolcow controller
+// A lolcow is retried after the longer of its exponential backoff
+// and its turn in the (shared) token bucket.
+type RateLimiterConfig struct {
+
+	// BaseDelay is the first backoff of a lolcow, doubled on each failure
+	// +optional
+	BaseDelay metav1.Duration `json:"baseDelay,omitempty"`
+
+	// MaxDelay caps the backoff of a lolcow
+	// +optional
+	MaxDelay metav1.Duration `json:"maxDelay,omitempty"`
+
+	// QPS is how many retries per second the bucket allows, over all lolcows
+	// +optional
+	QPS float64 `json:"qps,omitempty"`
+
+	// Burst is the size of the bucket
+	// +optional
+	Burst int `json:"burst,omitempty"`
+}
+
+// Complete returns the manager part of the configuration
+// This lets ctrl.Options.AndFrom read an OperatorConfig.
+func (c *OperatorConfig) Complete() (cfg.ControllerManagerConfigurationSpec, error) {
+	return c.ControllerManagerConfigurationSpec, nil
+}
@@ -0,0 +1,161 @@
+//go:build !ignore_autogenerated
+// +build !ignore_autogenerated
+
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LolcowConfig) DeepCopyInto(out *LolcowConfig) {
+	*out = *in
+	out.RateLimiter = in.RateLimiter
+	out.StuckQueueTimeout = in.StuckQueueTimeout
+	if in.WatchNamespaces != nil {
+		in, out := &in.WatchNamespaces, &out.WatchNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PropagateLabelPrefixes != nil {
+		in, out := &in.PropagateLabelPrefixes, &out.PropagateLabelPrefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PropagateAnnotationPrefixes != nil {
+		in, out := &in.PropagateAnnotationPrefixes, &out.PropagateAnnotationPrefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LolcowConfig.
+func (in *LolcowConfig) DeepCopy() *LolcowConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(LolcowConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorConfig) DeepCopyInto(out *OperatorConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ControllerManagerConfigurationSpec.DeepCopyInto(&out.ControllerManagerConfigurationSpec)
+	if in.Controllers != nil {
+		in, out := &in.Controllers, &out.Controllers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Lolcow.DeepCopyInto(&out.Lolcow)
+	if in.FeatureGates != nil {
+		in, out := &in.FeatureGates, &out.FeatureGates
+		*out = make(map[string]bool, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.WebhookCertificates.DeepCopyInto(&out.WebhookCertificates)
+	out.SecureMetrics = in.SecureMetrics
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfig.
+func (in *OperatorConfig) DeepCopy() *OperatorConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OperatorConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateLimiterConfig) DeepCopyInto(out *RateLimiterConfig) {
+	*out = *in
+	out.BaseDelay = in.BaseDelay
+	out.MaxDelay = in.MaxDelay
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimiterConfig.
+func (in *RateLimiterConfig) DeepCopy() *RateLimiterConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RateLimiterConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecureMetricsConfig) DeepCopyInto(out *SecureMetricsConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecureMetricsConfig.
+func (in *SecureMetricsConfig) DeepCopy() *SecureMetricsConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(SecureMetricsConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookCertificatesConfig) DeepCopyInto(out *WebhookCertificatesConfig) {
+	*out = *in
+	if in.MutatingWebhookConfigurations != nil {
+		in, out := &in.MutatingWebhookConfigurations, &out.MutatingWebhookConfigurations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ValidatingWebhookConfigurations != nil {
+		in, out := &in.ValidatingWebhookConfigurations, &out.ValidatingWebhookConfigurations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CustomResourceDefinitions != nil {
+		in, out := &in.CustomResourceDefinitions, &out.CustomResourceDefinitions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.Validity = in.Validity
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookCertificatesConfig.
+func (in *WebhookCertificatesConfig) DeepCopy() *WebhookCertificatesConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookCertificatesConfig)
+	in.DeepCopyInto(out)
+	return out
+}
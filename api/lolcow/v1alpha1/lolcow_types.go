@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	//	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -25,24 +26,164 @@ import (
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
 // LolcowSpec defines the desired state of Lolcow
+// +kubebuilder:validation:XValidation:rule="!has(self.port) || !has(self.serviceType) || self.serviceType != 'ClusterIP'",message="port is only allowed for NodePort or LoadBalancer services"
+// +kubebuilder:validation:XValidation:rule="!has(self.greeting) || !has(self.greetingFrom)",message="greeting and greetingFrom are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!has(oldSelf.serviceType) || has(self.serviceType)",message="serviceType can't be removed once set"
 type LolcowSpec struct {
-	// Port for lolcow
-	Port int32 `json:"port"`
+	// Port for lolcow, the node port of NodePort and LoadBalancer services.
+	// It's optional (Kubernetes picks one when unset), must be in the default node port range,
+	// and isn't allowed with serviceType ClusterIP. Without a serviceType, it's used unless the
+	// operator's --default-service-type is ClusterIP.
+	// +kubebuilder:validation:Minimum=30000
+	// +kubebuilder:validation:Maximum=32767
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// ServiceType exposes the lolcow, defaults to the operator's --default-service-type
+	// It can't be changed, so clients don't lose the address they know.
+	// +kubebuilder:validation:Enum=LoadBalancer;NodePort;ClusterIP
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="serviceType is immutable"
+	// +optional
+	ServiceType corev1.ServiceType `json:"serviceType,omitempty"`
+
+	// ContainerPort the lolcow web server listens on (exported as PORT)
+	// +kubebuilder:default=8080
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	ContainerPort int32 `json:"containerPort,omitempty"`
+
+	// ServicePort the lolcow Service exposes inside the cluster
+	// +kubebuilder:default=80
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	ServicePort int32 `json:"servicePort,omitempty"`
 
 	// Foo is an example field of Lolcow. Edit lolcow_types.go to remove/update
+	// +kubebuilder:validation:MaxLength=256
 	Greeting string `json:"greeting,omitempty"`
+
+	// GreetingFrom reads the greeting from a ConfigMap key, instead of greeting
+	// The pods read it when they start, so restart them to pick up changes.
+	// +optional
+	GreetingFrom *corev1.ConfigMapKeySelector `json:"greetingFrom,omitempty"`
+
+	// Image serves the greeting, defaults to the operator's --default-image
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// NetworkPolicy restricts ingress to the lolcow pods
+	// When unset, no NetworkPolicy is created
+	// +optional
+	NetworkPolicy *LolcowNetworkPolicy `json:"networkPolicy,omitempty"`
+
+	// ServiceAccountName to run the lolcow pods as (bring your own)
+	// When unset, a dedicated ServiceAccount named after the Lolcow is created
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// AutomountServiceAccountToken mounts the API token into the lolcow pods
+	// The lolcow never talks to the API server, so this defaults to false
+	// +optional
+	AutomountServiceAccountToken *bool `json:"automountServiceAccountToken,omitempty"`
+
+	// ImagePullSecrets to pull the lolcow image with
+	// These are attached to the dedicated ServiceAccount, or directly to the
+	// pods when serviceAccountName is set (we don't edit accounts we don't own)
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// Suspend stops reconciling the lolcow, leaving its resources as they are
+	// The lolcow.my.domain/paused annotation does the same
+	// +optional
+	Suspend *bool `json:"suspend,omitempty"`
+}
+
+// LolcowNetworkPolicy describes who is allowed to talk to the lolcow
+type LolcowNetworkPolicy struct {
+
+	// AllowedFrom lists the peers allowed to reach the lolcow port
+	// An empty list denies all ingress to the lolcow pods
+	// +optional
+	AllowedFrom []LolcowNetworkPeer `json:"allowedFrom,omitempty"`
+}
+
+// LolcowNetworkPeer is a single source allowed to reach the lolcow
+// Set a cidr, or a namespace and/or pod selector (not both)
+// +kubebuilder:validation:XValidation:rule="!has(self.cidr) || (!has(self.namespaceSelector) && !has(self.podSelector))",message="cidr may not be set with a namespaceSelector or podSelector"
+// +kubebuilder:validation:XValidation:rule="has(self.cidr) || has(self.namespaceSelector) || has(self.podSelector)",message="set a cidr, or a namespaceSelector and/or podSelector"
+// +kubebuilder:validation:XValidation:rule="!has(self.except) || has(self.cidr)",message="except may only be set with a cidr"
+type LolcowNetworkPeer struct {
+
+	// NamespaceSelector selects namespaces allowed to reach the lolcow
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// PodSelector selects pods allowed to reach the lolcow
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+
+	// CIDR is an IP block allowed to reach the lolcow, e.g., 10.0.0.0/16
+	// +optional
+	CIDR string `json:"cidr,omitempty"`
+
+	// Except lists CIDRs to carve out of CIDR
+	// +optional
+	Except []string `json:"except,omitempty"`
 }
 
+// Condition types reported on a Lolcow. Each owned resource reports its
+// own condition, and Ready is true when all of them are.
+const (
+	ConditionReady               = "Ready"
+	ConditionServiceAccountReady = "ServiceAccountReady"
+	ConditionDeploymentReady     = "DeploymentReady"
+	ConditionServiceReady        = "ServiceReady"
+	ConditionNetworkPolicyReady  = "NetworkPolicyReady"
+	ConditionPaused              = "Paused"
+)
+
+// LogLevelAnnotation set to "debug" on a Lolcow turns on debug logs for it
+const LogLevelAnnotation = "lolcow.my.domain/log-level"
+
+// PausedAnnotation set to "true" on a Lolcow stops reconciling it, like spec.suspend
+const PausedAnnotation = "lolcow.my.domain/paused"
+
+// MinNodePort and MaxNodePort bound spec.port, the default --service-node-port-range
+const (
+	MinNodePort = 30000
+	MaxNodePort = 32767
+)
+
+// MaxGreetingLength is the longest greeting a lolcow says
+const MaxGreetingLength = 256
+
 // LolcowStatus defines the observed state of Lolcow
 type LolcowStatus struct {
 	DeployedService bool `json:"deployed_service,omitempty"`
 
+	// ObservedGeneration is the last Lolcow generation every resource was reconciled for.
+	// Each resource's condition has its own: the last generation it was applied for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions of the lolcow and each resource it owns
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 }
 
+//+genclient
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Greeting",type=string,JSONPath=`.spec.greeting`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Lolcow is the Schema for the lolcows API
 type Lolcow struct {
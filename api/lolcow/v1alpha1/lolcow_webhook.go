@@ -0,0 +1,208 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// Defaults for the ports, the same as the CRD's
+const (
+	DefaultContainerPort int32 = 8080
+	DefaultServicePort   int32 = 80
+)
+
+// Log levels the LogLevelAnnotation accepts
+var logLevels = []string{"debug", "info"}
+
+// SetupWebhookWithManager serves the defaulting and validating webhooks
+// The same rules run offline in lolcow-operator validate.
+func (r *Lolcow) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-my-domain-v1alpha1-lolcow,mutating=true,failurePolicy=fail,sideEffects=None,groups=my.domain,resources=lolcows,verbs=create;update,versions=v1alpha1,name=mlolcow.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &Lolcow{}
+
+// Default fills in what the lolcow didn't say
+func (r *Lolcow) Default() {
+	if r.Spec.ContainerPort == 0 {
+		r.Spec.ContainerPort = DefaultContainerPort
+	}
+	if r.Spec.ServicePort == 0 {
+		r.Spec.ServicePort = DefaultServicePort
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-my-domain-v1alpha1-lolcow,mutating=false,failurePolicy=fail,sideEffects=None,groups=my.domain,resources=lolcows,verbs=create;update,versions=v1alpha1,name=vlolcow.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &Lolcow{}
+
+// ValidateCreate checks a new lolcow
+func (r *Lolcow) ValidateCreate() error {
+	return r.invalid(ValidateLolcow(r))
+}
+
+// ValidateUpdate checks a changed lolcow
+func (r *Lolcow) ValidateUpdate(old runtime.Object) error {
+	return r.invalid(ValidateLolcowUpdate(r, old.(*Lolcow)))
+}
+
+// ValidateDelete lets any lolcow go
+func (r *Lolcow) ValidateDelete() error {
+	return nil
+}
+
+// invalid is the API error for a list of problems, or nil if there are none
+func (r *Lolcow) invalid(errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Lolcow").GroupKind(), r.Name, errs)
+}
+
+// ValidateLolcow lists everything wrong with a lolcow
+func ValidateLolcow(lolcow *Lolcow) field.ErrorList {
+	errs := validateAnnotations(lolcow.Annotations, field.NewPath("metadata", "annotations"))
+	return append(errs, validateSpec(&lolcow.Spec, field.NewPath("spec"))...)
+}
+
+// ValidateLolcowUpdate lists everything wrong with a change to a lolcow
+// The immutable fields mirror the CRD's transition rules.
+func ValidateLolcowUpdate(lolcow, old *Lolcow) field.ErrorList {
+	errs := ValidateLolcow(lolcow)
+	path := field.NewPath("spec", "serviceType")
+	switch {
+	case old.Spec.ServiceType == "":
+	case lolcow.Spec.ServiceType == "":
+		errs = append(errs, field.Forbidden(path, "serviceType can't be removed once set"))
+	case lolcow.Spec.ServiceType != old.Spec.ServiceType:
+		errs = append(errs, field.Invalid(path, lolcow.Spec.ServiceType, "serviceType is immutable"))
+	}
+	return errs
+}
+
+func validateAnnotations(annotations map[string]string, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if value, ok := annotations[PausedAnnotation]; ok {
+		if _, err := strconv.ParseBool(value); err != nil {
+			errs = append(errs, field.Invalid(path.Key(PausedAnnotation), value, "must be true or false"))
+		}
+	}
+	if value, ok := annotations[LogLevelAnnotation]; ok {
+		found := false
+		for _, level := range logLevels {
+			found = found || value == level
+		}
+		if !found {
+			errs = append(errs, field.NotSupported(path.Key(LogLevelAnnotation), value, logLevels))
+		}
+	}
+	return errs
+}
+
+func validateSpec(spec *LolcowSpec, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	for _, port := range []struct {
+		name  string
+		value int32
+	}{{"containerPort", spec.ContainerPort}, {"servicePort", spec.ServicePort}} {
+		if port.value == 0 {
+			continue
+		}
+		for _, msg := range validation.IsValidPortNum(int(port.value)) {
+			errs = append(errs, field.Invalid(path.Child(port.name), port.value, msg))
+		}
+	}
+
+	if spec.Port != 0 && (spec.Port < MinNodePort || spec.Port > MaxNodePort) {
+		errs = append(errs, field.Invalid(path.Child("port"), spec.Port, fmt.Sprintf("must be between %d and %d, inclusive", MinNodePort, MaxNodePort)))
+	}
+
+	if spec.GreetingFrom != nil && spec.GreetingFrom.Name == "" {
+		errs = append(errs, field.Required(path.Child("greetingFrom", "name"), ""))
+	}
+	if strings.ContainsAny(spec.Image, " \t\n") {
+		errs = append(errs, field.Invalid(path.Child("image"), spec.Image, "must not contain whitespace"))
+	}
+	if spec.ServiceAccountName != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(spec.ServiceAccountName) {
+			errs = append(errs, field.Invalid(path.Child("serviceAccountName"), spec.ServiceAccountName, msg))
+		}
+	}
+	for i, secret := range spec.ImagePullSecrets {
+		if secret.Name == "" {
+			errs = append(errs, field.Required(path.Child("imagePullSecrets").Index(i).Child("name"), ""))
+		}
+	}
+	if spec.NetworkPolicy != nil {
+		for i, peer := range spec.NetworkPolicy.AllowedFrom {
+			errs = append(errs, validatePeer(&peer, path.Child("networkPolicy", "allowedFrom").Index(i))...)
+		}
+	}
+	return errs
+}
+
+// validatePeer checks a peer has a cidr, or selectors, but not both
+func validatePeer(peer *LolcowNetworkPeer, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	hasSelector := peer.NamespaceSelector != nil || peer.PodSelector != nil
+	switch {
+	case peer.CIDR != "" && hasSelector:
+		errs = append(errs, field.Forbidden(path.Child("cidr"), "may not be set with a namespaceSelector or podSelector"))
+	case peer.CIDR == "" && !hasSelector:
+		errs = append(errs, field.Required(path, "set a cidr, or a namespaceSelector and/or podSelector"))
+	case peer.CIDR == "" && len(peer.Except) > 0:
+		errs = append(errs, field.Forbidden(path.Child("except"), "may only be set with a cidr"))
+	}
+	if peer.NamespaceSelector != nil {
+		errs = append(errs, metav1validation.ValidateLabelSelector(peer.NamespaceSelector, path.Child("namespaceSelector"))...)
+	}
+	if peer.PodSelector != nil {
+		errs = append(errs, metav1validation.ValidateLabelSelector(peer.PodSelector, path.Child("podSelector"))...)
+	}
+	if peer.CIDR == "" {
+		return errs
+	}
+	_, block, err := net.ParseCIDR(peer.CIDR)
+	if err != nil {
+		return append(errs, field.Invalid(path.Child("cidr"), peer.CIDR, "must be a CIDR, e.g., 10.0.0.0/16"))
+	}
+	for i, except := range peer.Except {
+		ip, _, err := net.ParseCIDR(except)
+		if err != nil {
+			errs = append(errs, field.Invalid(path.Child("except").Index(i), except, "must be a CIDR, e.g., 10.0.1.0/24"))
+		} else if !block.Contains(ip) {
+			errs = append(errs, field.Invalid(path.Child("except").Index(i), except, "must be inside the cidr "+peer.CIDR))
+		}
+	}
+	return errs
+}
@@ -17,19 +17,157 @@ limitations under the License.
 package core
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
 	ctrl "sigs.k8s.io/controller-runtime"
+
+	"vsoch/lolcow-operator/api/config/v1alpha1"
+	"vsoch/lolcow-operator/pkg/rbac"
 )
 
 const updateChBuffer = 10
 
-// SetupControllers sets up all controllers.
-func SetupControllers(mgr ctrl.Manager) (string, error) {
+// SetupFunc adds a controller to the manager, configured from the operator config
+type SetupFunc func(mgr ctrl.Manager, config *v1alpha1.OperatorConfig) error
+
+// AllControllers selects every registered controller (that is on by default)
+const AllControllers = "*"
+
+// PermissionsFunc lists what a controller needs from the API server, which
+// the manager checks at startup (see pkg/rbac)
+type PermissionsFunc func(config *v1alpha1.OperatorConfig) []rbac.Permission
+
+type registration struct {
+	setup       SetupFunc
+	permissions PermissionsFunc
+	enabled     bool
+}
+
+var (
+	registryMutex sync.Mutex
+	registry      = map[string]registration{}
+)
+
+// Register adds a controller to the registry, usually from an init function
+// enabledByDefault says if "*" selects it. Registering a name twice panics.
+func Register(name string, enabledByDefault bool, setup SetupFunc) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("controller %q is already registered", name))
+	}
+	registry[name] = registration{setup: setup, enabled: enabledByDefault}
+}
+
+// RegisterPermissions sets what a registered controller needs, usually
+// from the same init function
+func RegisterPermissions(name string, permissions PermissionsFunc) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	r, ok := registry[name]
+	if !ok {
+		panic(fmt.Sprintf("controller %q is not registered", name))
+	}
+	r.permissions = permissions
+	registry[name] = r
+}
+
+// Permissions are what the selected controllers need
+func Permissions(config *v1alpha1.OperatorConfig) ([]rbac.Permission, error) {
+	names, err := Selected(config.Controllers)
+	if err != nil {
+		return nil, err
+	}
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	permissions := []rbac.Permission{}
+	for _, name := range names {
+		if needs := registry[name].permissions; needs != nil {
+			permissions = append(permissions, needs(config)...)
+		}
+	}
+	return permissions, nil
+}
+
+// Names are the registered controllers, sorted
+func Names() []string {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	return names()
+}
+
+func names() []string {
+	names := []string{}
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Selected works out which controllers to run, in order, from a selection
+// like --controllers=*,-foo or --controllers=lolcow. An empty selection is "*".
+func Selected(selection []string) ([]string, error) {
+	if len(selection) == 0 {
+		selection = []string{AllControllers}
+	}
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	enabled := map[string]bool{}
+	for _, item := range selection {
+		name := strings.TrimPrefix(item, "-")
+		switch {
+		case item == AllControllers:
+			for name, r := range registry {
+				if _, ok := enabled[name]; !ok {
+					enabled[name] = r.enabled
+				}
+			}
+			continue
+		case name == AllControllers:
+			return nil, fmt.Errorf("%q is not a controller, use %q to turn them all on", item, AllControllers)
+		}
+		disable := strings.HasPrefix(item, "-")
+
+		// Turning off a controller this build doesn't have is fine
+		if _, ok := registry[name]; !ok {
+			if disable {
+				continue
+			}
+			return nil, fmt.Errorf("unknown controller %q, known controllers are %s", name, strings.Join(names(), ", "))
+		}
+		enabled[name] = !disable
+	}
+
+	selected := []string{}
+	for name, on := range enabled {
+		if on {
+			selected = append(selected, name)
+		}
+	}
+	sort.Strings(selected)
+	return selected, nil
+}
+
+// SetupControllers sets up the selected controllers. If one fails,
+// its name is returned with the error.
+func SetupControllers(mgr ctrl.Manager, config *v1alpha1.OperatorConfig) (string, error) {
+	names, err := Selected(config.Controllers)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range names {
+		registryMutex.Lock()
+		setup := registry[name].setup
+		registryMutex.Unlock()
 
-	// TODO: we can setup other controllers here
-	// For now this does nothing :)
-	//recon := NewLolcowReconciler(mgr.GetClient(), mgr.GetScheme())
-	//if err := recon.SetupWithManager(mgr); err != nil {
-	//	return "Lolcow", err
-	//}
+		if err := setup(mgr, config); err != nil {
+			return name, err
+		}
+	}
 	return "", nil
 }
@@ -0,0 +1,134 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"vsoch/lolcow-operator/api/config/v1alpha1"
+	"vsoch/lolcow-operator/pkg/rbac"
+)
+
+var _ = Describe("Registry", func() {
+	var (
+		saved map[string]registration
+		setup []string
+	)
+
+	// register adds a fake controller that records its setup
+	register := func(name string, enabledByDefault bool, err error) {
+		Register(name, enabledByDefault, func(mgr ctrl.Manager, config *v1alpha1.OperatorConfig) error {
+			setup = append(setup, name)
+			return err
+		})
+	}
+
+	BeforeEach(func() {
+		registryMutex.Lock()
+		saved, registry = registry, map[string]registration{}
+		registryMutex.Unlock()
+		setup = nil
+
+		register("lolcow", true, nil)
+		register("moose", true, nil)
+		register("experimental", false, nil)
+	})
+
+	AfterEach(func() {
+		registryMutex.Lock()
+		registry = saved
+		registryMutex.Unlock()
+	})
+
+	It("lists the names, sorted", func() {
+		Expect(Names()).To(Equal([]string{"experimental", "lolcow", "moose"}))
+	})
+
+	It("refuses a name twice", func() {
+		Expect(func() { register("lolcow", true, nil) }).To(PanicWith(`controller "lolcow" is already registered`))
+	})
+
+	DescribeTable("Selected",
+		func(selection []string, expected []string) {
+			Expect(Selected(selection)).To(Equal(expected))
+		},
+		Entry("nothing, the defaults", nil, []string{"lolcow", "moose"}),
+		Entry("all, the defaults", []string{"*"}, []string{"lolcow", "moose"}),
+		Entry("all but one", []string{"*", "-moose"}, []string{"lolcow"}),
+		Entry("all but one, in any order", []string{"-moose", "*"}, []string{"lolcow"}),
+		Entry("all and one off by default", []string{"*", "experimental"}, []string{"experimental", "lolcow", "moose"}),
+		Entry("only one", []string{"moose"}, []string{"moose"}),
+		Entry("only one off by default", []string{"experimental"}, []string{"experimental"}),
+		Entry("one turned on, then off", []string{"moose", "-moose"}, []string{}),
+		Entry("all turned off", []string{"-lolcow", "-moose"}, []string{}),
+		Entry("one disabled by default, turned off", []string{"*", "-experimental"}, []string{"lolcow", "moose"}),
+		Entry("turning off one this build doesn't have", []string{"*", "-goat"}, []string{"lolcow", "moose"}),
+	)
+
+	DescribeTable("Selected, with a bad selection",
+		func(selection []string, expected string) {
+			_, err := Selected(selection)
+			Expect(err).To(MatchError(expected))
+		},
+		Entry("an unknown controller", []string{"*", "goat"},
+			`unknown controller "goat", known controllers are experimental, lolcow, moose`),
+		Entry("turning all off", []string{"-*"}, `"-*" is not a controller, use "*" to turn them all on`),
+	)
+
+	Context("the selected controllers", func() {
+		It("are set up in order", func() {
+			name, err := SetupControllers(nil, &v1alpha1.OperatorConfig{Controllers: []string{"*", "experimental"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(BeEmpty())
+			Expect(setup).To(Equal([]string{"experimental", "lolcow", "moose"}))
+		})
+
+		It("stop at the first one to fail, and name it", func() {
+			register("broken", true, errors.New("no cows"))
+			name, err := SetupControllers(nil, &v1alpha1.OperatorConfig{})
+			Expect(err).To(MatchError("no cows"))
+			Expect(name).To(Equal("broken"))
+			Expect(setup).To(Equal([]string{"broken"}))
+		})
+
+		It("are not set up on a bad selection", func() {
+			_, err := SetupControllers(nil, &v1alpha1.OperatorConfig{Controllers: []string{"goat"}})
+			Expect(err).To(HaveOccurred())
+			Expect(setup).To(BeEmpty())
+		})
+
+		It("need their permissions, and only theirs", func() {
+			need := func(resource string) PermissionsFunc {
+				return func(config *v1alpha1.OperatorConfig) []rbac.Permission {
+					return []rbac.Permission{{Resource: resource, Verbs: []string{"get"}}}
+				}
+			}
+			RegisterPermissions("lolcow", need("lolcows"))
+			RegisterPermissions("experimental", need("experiments"))
+			permissions, err := Permissions(&v1alpha1.OperatorConfig{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(permissions).To(Equal([]rbac.Permission{{Resource: "lolcows", Verbs: []string{"get"}}}))
+
+			Expect(func() { RegisterPermissions("goat", need("goats")) }).To(PanicWith(`controller "goat" is not registered`))
+		})
+	})
+})
@@ -0,0 +1,149 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+	"vsoch/lolcow-operator/pkg/config"
+)
+
+// benchmarkLolcows is how many lolcows each run creates
+const benchmarkLolcows = 1000
+
+// BenchmarkReconcile measures how many lolcows a second the controller
+// reconciles (to a first status), for a few --max-concurrent-reconciles.
+// Run it with make bench, which sets up the envtest binaries.
+func BenchmarkReconcile(b *testing.B) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		b.Skip("KUBEBUILDER_ASSETS is not set, see make bench")
+	}
+
+	testEnv := &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "config", "crd", "bases")},
+		ErrorIfCRDPathMissing: true,
+	}
+	restConfig, err := testEnv.Start()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer testEnv.Stop()
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		b.Fatal(err)
+	}
+	if err := api.AddToScheme(scheme); err != nil {
+		b.Fatal(err)
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// Each run has its own manager, and a namespace per iteration
+			mgr, err := ctrl.NewManager(restConfig, ctrl.Options{Scheme: scheme, MetricsBindAddress: "0"})
+			if err != nil {
+				b.Fatal(err)
+			}
+			err = NewLolcowReconciler(mgr.GetClient(), mgr.GetScheme(),
+				WithRecorder(mgr.GetEventRecorderFor("lolcow-controller")),
+				WithDefaultServiceType(corev1.ServiceTypeClusterIP),
+				WithMaxConcurrentReconciles(workers),
+				WithRateLimiter(config.NewRateLimiter(config.New().Lolcow.RateLimiter)),
+			).SetupWithManager(mgr)
+			if err != nil {
+				b.Fatal(err)
+			}
+			go func() {
+				if err := mgr.Start(ctx); err != nil {
+					b.Error(err)
+				}
+			}()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				namespace := fmt.Sprintf("bench-%d-%d", workers, i)
+				start := time.Now()
+				createLolcows(ctx, b, c, namespace, benchmarkLolcows)
+				waitForLolcows(ctx, b, c, namespace, benchmarkLolcows)
+				elapsed := time.Since(start)
+				b.ReportMetric(float64(benchmarkLolcows)/elapsed.Seconds(), "lolcows/s")
+			}
+		})
+	}
+}
+
+// createLolcows makes a namespace with count lolcows in it
+func createLolcows(ctx context.Context, b *testing.B, c client.Client, namespace string, count int) {
+	b.Helper()
+	if err := c.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}); err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < count; i++ {
+		lolcow := &api.Lolcow{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("lolcow-%d", i), Namespace: namespace},
+			Spec:       api.LolcowSpec{Greeting: "Moo"},
+		}
+		if err := c.Create(ctx, lolcow); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// waitForLolcows waits until every lolcow has a status for its generation
+// There is no kube-controller-manager in envtest, so none of them get Ready.
+func waitForLolcows(ctx context.Context, b *testing.B, c client.Client, namespace string, count int) {
+	b.Helper()
+	deadline := time.Now().Add(10 * time.Minute)
+	for time.Now().Before(deadline) {
+		lolcows := &api.LolcowList{}
+		if err := c.List(ctx, lolcows, client.InNamespace(namespace)); err != nil {
+			b.Fatal(err)
+		}
+		done := 0
+		for _, lolcow := range lolcows.Items {
+			if lolcow.Status.ObservedGeneration == lolcow.Generation {
+				done++
+			}
+		}
+		if done == count {
+			return
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	b.Fatalf("timed out waiting for %d lolcows in %s", count, namespace)
+}
@@ -0,0 +1,323 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	logctrl "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"vsoch/lolcow-operator/pkg/logging"
+	"vsoch/lolcow-operator/pkg/metrics"
+	"vsoch/lolcow-operator/pkg/tracing"
+)
+
+// LolcowResources have both meta and runtime interfaces
+// These are the child objects a Lolcow owns
+type LolcowResources interface {
+	metav1.Object
+	runtime.Object
+}
+
+// component declares one child object of a Lolcow. The framework below
+// does the Get / Create / Update (and Delete, if it's disabled), sets the
+// owner reference, and works out what changed.
+type component struct {
+
+	// name of the component, e.g., deployment
+	name string
+
+	// condition is the condition type the component reports
+	condition string
+
+	// object is an empty object of the kind, for the watches
+	object LolcowResources
+
+	// predicates filter the events of the watch on object (nil passes them all)
+	predicates []predicate.Predicate
+
+	// enabled says if the lolcow wants the object at all (nil is always)
+	// A disabled component has its object removed, if we own it.
+	enabled func(state *lolcowState) bool
+
+	// desired builds the object we want to exist
+	desired func(state *lolcowState) LolcowResources
+
+	// mutate copies the fields we own from desired onto existing
+	// Anything we don't set (defaults, other controllers) should be left alone
+	mutate func(ctx context.Context, state *lolcowState, existing, desired LolcowResources) error
+
+	// ready checks the object in the cluster (nil is ready when it exists)
+	ready func(state *lolcowState, object LolcowResources) phaseResult
+}
+
+// componentResult is what happened to a component's object
+type componentResult struct {
+	phaseResult
+
+	// action is created, updated, deleted, or empty if nothing was done
+	action string
+
+	// changes lists the fields an update changed
+	changes []string
+
+	// object is the child as we left it, nil if there is none
+	object LolcowResources
+}
+
+// Actions the framework takes on an object
+const (
+	ActionCreated = "created"
+	ActionUpdated = "updated"
+	ActionDeleted = "deleted"
+)
+
+// newObject returns an empty object of the same kind as the given one
+func (r *LolcowReconciler) newObject(like LolcowResources) (LolcowResources, error) {
+	gvk, err := apiutil.GVKForObject(like, r.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	object, err := r.Scheme.New(gvk)
+	if err != nil {
+		return nil, err
+	}
+	return object.(LolcowResources), nil
+}
+
+// reconcileComponent converges one component's object
+func (r *LolcowReconciler) reconcileComponent(ctx context.Context, state *lolcowState, c component) (componentResult, error) {
+	instance := state.instance
+	kind := reflect.TypeOf(c.object).Elem().Name()
+	log := logctrl.FromContext(ctx).WithValues(logging.KeyPhase, c.name, logging.KeyKind, kind)
+
+	// What do we want, and what do we have?
+	var desired LolcowResources
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+	enabled := c.enabled == nil || c.enabled(state)
+	if enabled {
+		desired = c.desired(state)
+		key = types.NamespacedName{Name: desired.GetName(), Namespace: desired.GetNamespace()}
+		if err := ctrl.SetControllerReference(instance, desired, r.Scheme); err != nil {
+			return componentResult{}, err
+		}
+	}
+	existing, err := r.newObject(c.object)
+	if err != nil {
+		return componentResult{}, err
+	}
+	err = traced(ctx, "Get", kind, key, func(ctx context.Context) error {
+		return r.Get(ctx, key, existing)
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "Failed to get "+kind, logging.KeyChild, key.Name)
+		metrics.ChildErrors.WithLabelValues(kind, "get").Inc()
+		r.warning(instance, EventGetFailed, fmt.Sprintf("Failed to get %s %s: %s", kind, key.Name, err))
+		return componentResult{}, err
+	}
+	found := err == nil
+
+	// Not wanted: remove it, but only if it's ours
+	if !enabled {
+		result := componentResult{phaseResult: phaseResult{ready: true, reason: reasonNotRequested, message: "No " + kind + " requested"}}
+		if found && metav1.IsControlledBy(existing, instance) {
+			log.Info("🧹 Removing "+kind+" 🧹", logging.KeyChild, existing.GetName())
+			err = traced(ctx, "Delete", kind, key, func(ctx context.Context) error {
+				return r.Delete(ctx, existing)
+			})
+			if err != nil && !errors.IsNotFound(err) {
+				log.Error(err, "Failed to delete "+kind, logging.KeyChild, existing.GetName())
+				metrics.ChildErrors.WithLabelValues(kind, "delete").Inc()
+				r.warning(instance, EventDeleteFailed, fmt.Sprintf("Failed to delete %s %s: %s", kind, existing.GetName(), err))
+				return componentResult{}, err
+			}
+			r.event(instance, EventDeleted, fmt.Sprintf("Deleted %s %s", kind, existing.GetName()))
+			result.action = ActionDeleted
+			result.object = existing
+		}
+		return result, nil
+	}
+
+	// Not found: create it
+	if !found {
+		log.Info("✨ Creating a new "+kind+" ✨", logging.KeyChild, desired.GetName())
+		err = traced(ctx, "Create", kind, key, func(ctx context.Context) error {
+			return r.Create(ctx, desired)
+		})
+		if err != nil {
+			log.Error(err, "❌ Failed to create new "+kind, logging.KeyChild, desired.GetName())
+			metrics.ChildErrors.WithLabelValues(kind, "create").Inc()
+			r.warning(instance, EventCreateFailed, fmt.Sprintf("Failed to create %s %s: %s", kind, desired.GetName(), err))
+			return componentResult{}, err
+		}
+		r.event(instance, EventCreated, fmt.Sprintf("Created %s %s", kind, desired.GetName()))
+		state.observe(c.name, desired)
+		return componentResult{phaseResult: c.check(state, desired), action: ActionCreated, object: desired}, nil
+	}
+
+	// Found: make sure it's ours, and bring the fields we own up to date
+	before := existing.DeepCopyObject()
+	if err := ctrl.SetControllerReference(instance, existing, r.Scheme); err != nil {
+		log.Error(err, "Refusing to manage "+kind, logging.KeyChild, existing.GetName())
+		r.warning(instance, EventOwnershipFailed, fmt.Sprintf("%s %s is managed by someone else: %s", kind, existing.GetName(), err))
+		return componentResult{}, err
+	}
+	if err := c.mutate(logctrl.IntoContext(ctx, log), state, existing, desired); err != nil {
+		return componentResult{}, err
+	}
+	state.observe(c.name, existing)
+	changes, err := diffObjects(before, existing)
+	if err != nil {
+		return componentResult{}, err
+	}
+	if len(changes) == 0 {
+		log.V(1).Info(kind+" is up to date", logging.KeyChild, existing.GetName())
+		return componentResult{phaseResult: c.check(state, existing), object: existing}, nil
+	}
+
+	log.Info("🔁 Updating "+kind+" 🔁", logging.KeyChild, existing.GetName(), "changes", changes)
+	err = traced(ctx, "Update", kind, key, func(ctx context.Context) error {
+		return r.Update(ctx, existing)
+	})
+	if err != nil {
+		log.Error(err, "Failed to update "+kind, logging.KeyChild, existing.GetName())
+		metrics.ChildErrors.WithLabelValues(kind, "update").Inc()
+		r.warning(instance, EventUpdateFailed, fmt.Sprintf("Failed to update %s %s: %s", kind, existing.GetName(), err))
+		return componentResult{}, err
+	}
+	reason := updateReason(instance, c.condition, changes)
+	r.event(instance, reason, fmt.Sprintf("Updated %s %s: %s", kind, existing.GetName(), strings.Join(changes, ", ")))
+	switch reason {
+	case EventGreetingChanged:
+		metrics.GreetingUpdates.Inc()
+	case EventPortChanged:
+		metrics.PortChanges.WithLabelValues(kind).Inc()
+	case EventDriftCorrected:
+		metrics.DriftCorrections.WithLabelValues(kind).Inc()
+	}
+	return componentResult{phaseResult: c.check(state, existing), action: ActionUpdated, changes: changes, object: existing}, nil
+}
+
+// traced makes a call on a child in its own span, e.g., "Get Deployment"
+// A Get that finds nothing is not an error for the span.
+func traced(ctx context.Context, operation, kind string, key types.NamespacedName, call func(context.Context) error) error {
+	ctx, span := tracing.Start(ctx, operation+" "+kind,
+		attribute.String("k8s.kind", kind),
+		attribute.String("k8s.namespace", key.Namespace),
+		attribute.String("k8s.name", key.Name),
+	)
+	err := call(ctx)
+	if errors.IsNotFound(err) {
+		span.SetAttributes(attribute.Bool("k8s.found", false))
+		tracing.End(span, nil)
+		return err
+	}
+	tracing.End(span, err)
+	return err
+}
+
+// check runs the component's readiness check
+func (c component) check(state *lolcowState, object LolcowResources) phaseResult {
+	if c.ready == nil {
+		return phaseResult{ready: true, reason: reasonReady, message: reflect.TypeOf(c.object).Elem().Name() + " " + object.GetName() + " is ready"}
+	}
+	return c.ready(state, object)
+}
+
+// diffObjects lists the fields that differ between two objects, as
+// "path: old -> new". Status and server-managed metadata are ignored.
+func diffObjects(before, after runtime.Object) ([]string, error) {
+	var left, right map[string]interface{}
+	for _, pair := range []struct {
+		object runtime.Object
+		into   *map[string]interface{}
+	}{{before, &left}, {after, &right}} {
+		raw, err := json.Marshal(pair.object)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, pair.into); err != nil {
+			return nil, err
+		}
+		delete(*pair.into, "status")
+		if metadata, ok := (*pair.into)["metadata"].(map[string]interface{}); ok {
+			for _, field := range []string{"resourceVersion", "managedFields", "generation", "creationTimestamp", "uid"} {
+				delete(metadata, field)
+			}
+		}
+	}
+	changes := []string{}
+	diffValues("", left, right, &changes)
+	sort.Strings(changes)
+	return changes, nil
+}
+
+// diffValues walks two decoded JSON values, recording the paths that differ
+func diffValues(path string, left, right interface{}, changes *[]string) {
+	leftMap, leftIsMap := left.(map[string]interface{})
+	rightMap, rightIsMap := right.(map[string]interface{})
+	if leftIsMap && rightIsMap {
+		for key := range leftMap {
+			diffValues(joinPath(path, key), leftMap[key], rightMap[key], changes)
+		}
+		for key := range rightMap {
+			if _, ok := leftMap[key]; !ok {
+				diffValues(joinPath(path, key), nil, rightMap[key], changes)
+			}
+		}
+		return
+	}
+	leftList, leftIsList := left.([]interface{})
+	rightList, rightIsList := right.([]interface{})
+	if leftIsList && rightIsList && len(leftList) == len(rightList) {
+		for i := range leftList {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), leftList[i], rightList[i], changes)
+		}
+		return
+	}
+	if !reflect.DeepEqual(left, right) {
+		*changes = append(*changes, fmt.Sprintf("%s: %s -> %s", path, jsonValue(left), jsonValue(right)))
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func jsonValue(value interface{}) string {
+	if value == nil {
+		return "<none>"
+	}
+	raw, _ := json.Marshal(value)
+	return string(raw)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Diffs", func() {
+	DescribeTable("diffValues",
+		func(left, right interface{}, expected []string) {
+			changes := []string{}
+			diffValues("spec", left, right, &changes)
+			Expect(changes).To(ConsistOf(expected))
+		},
+		Entry("equal values", "moo", "moo", []string{}),
+		Entry("a changed value", "moo", "baa", []string{`spec: "moo" -> "baa"`}),
+		Entry("a nested map",
+			map[string]interface{}{"a": map[string]interface{}{"b": 1.0, "c": "same"}},
+			map[string]interface{}{"a": map[string]interface{}{"b": 2.0, "c": "same"}},
+			[]string{"spec.a.b: 1 -> 2"}),
+		Entry("an added key",
+			map[string]interface{}{},
+			map[string]interface{}{"a": true},
+			[]string{"spec.a: <none> -> true"}),
+		Entry("a removed key",
+			map[string]interface{}{"a": map[string]interface{}{"b": "gone"}},
+			map[string]interface{}{"a": map[string]interface{}{}},
+			[]string{`spec.a.b: "gone" -> <none>`}),
+		Entry("a removed map",
+			map[string]interface{}{"a": map[string]interface{}{"b": "gone"}},
+			map[string]interface{}{},
+			[]string{`spec.a: {"b":"gone"} -> <none>`}),
+		Entry("lists of the same length, by index",
+			[]interface{}{"a", map[string]interface{}{"port": 80.0}},
+			[]interface{}{"a", map[string]interface{}{"port": 8080.0}},
+			[]string{"spec[1].port: 80 -> 8080"}),
+		Entry("lists of different lengths, whole",
+			[]interface{}{"a"},
+			[]interface{}{"a", "b"},
+			[]string{`spec: ["a"] -> ["a","b"]`}),
+		Entry("a map replaced by a list",
+			map[string]interface{}{"a": 1.0},
+			[]interface{}{1.0},
+			[]string{`spec: {"a":1} -> [1]`}),
+	)
+
+	It("sorts the changes", func() {
+		before := &corev1.ConfigMap{Data: map[string]string{"c": "1", "a": "1", "b": "1"}}
+		after := &corev1.ConfigMap{Data: map[string]string{"c": "2", "a": "2", "b": "2"}}
+		changes, err := diffObjects(before, after)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes).To(Equal([]string{`data.a: "1" -> "2"`, `data.b: "1" -> "2"`, `data.c: "1" -> "2"`}))
+	})
+
+	Context("diffObjects", func() {
+		var before, after *corev1.Service
+
+		BeforeEach(func() {
+			before = &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "hello-world", Labels: map[string]string{"app": "lolcow"}},
+				Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+			}
+			after = before.DeepCopy()
+		})
+
+		It("finds nothing when nothing changed", func() {
+			changes, err := diffObjects(before, after)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changes).To(BeEmpty())
+		})
+
+		It("ignores the status and the fields the server sets", func() {
+			after.ResourceVersion = "42"
+			after.Generation = 2
+			after.UID = "6b1a3c2e-0d6f-4c1e-9a57-7d0e0c9a1f00"
+			after.CreationTimestamp = metav1.Now()
+			after.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kubectl"}}
+			after.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "10.0.0.1"}}
+			changes, err := diffObjects(before, after)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changes).To(BeEmpty())
+		})
+
+		It("lists the spec and metadata changes", func() {
+			after.Labels["app"] = "lolcow-2"
+			after.Labels["team"] = "cows"
+			after.Spec.Ports[0].Port = 8080
+			after.Spec.Ports[0].NodePort = 30080
+			changes, err := diffObjects(before, after)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changes).To(Equal([]string{
+				`metadata.labels.app: "lolcow" -> "lolcow-2"`,
+				`metadata.labels.team: <none> -> "cows"`,
+				`spec.ports[0].nodePort: <none> -> 30080`,
+				`spec.ports[0].port: 80 -> 8080`,
+			}))
+		})
+
+		It("lists a removed label", func() {
+			after.Labels = nil
+			changes, err := diffObjects(before, after)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changes).To(Equal([]string{`metadata.labels: {"app":"lolcow"} -> <none>`}))
+		})
+	})
+})
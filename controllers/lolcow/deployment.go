@@ -17,57 +17,183 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
+	"fmt"
+	"strconv"
+
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	ctrl "sigs.k8s.io/controller-runtime"
+	logctrl "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+	"vsoch/lolcow-operator/pkg/config"
 )
 
-// labels fetches and sets labels
-func labels(v *api.Lolcow, tier string) map[string]string {
-	return map[string]string{
-		"app":             "lolcow",
-		"visitorssite_cr": v.Name,
-		"tier":            tier,
+// containerPort is the port the lolcow web server listens on
+// The CRD (or webhook) defaulting usually fills it in already.
+func containerPort(v *api.Lolcow) int32 {
+	if v.Spec.ContainerPort == 0 {
+		return api.DefaultContainerPort
+	}
+	return v.Spec.ContainerPort
+}
+
+// image is the container that serves the greeting, the lolcow's own or the default
+func (r *LolcowReconciler) image(v *api.Lolcow) string {
+	if v.Spec.Image != "" {
+		return v.Spec.Image
 	}
+	if r.DefaultImage == "" {
+		return config.DefaultImage
+	}
+	return r.DefaultImage
+}
+
+// greetingEnv holds the greeting from a ConfigMap (greetingFrom), which
+// Kubernetes expands in the command
+const greetingEnv = "LOLCOW_GREETING"
+
+// greeting is the lolcow's own, from its ConfigMap, or the greeter's if it doesn't have one
+func (r *LolcowReconciler) greeting(v *api.Lolcow) string {
+	if v.Spec.GreetingFrom != nil {
+		return "$(" + greetingEnv + ")"
+	}
+	if v.Spec.Greeting == "" && r.Greeter != nil {
+		return r.Greeter.Greeting
+	}
+	return v.Spec.Greeting
 }
 
 // Create a Deployment for the Nginx server.
 func (r *LolcowReconciler) createDeployment(instance *api.Lolcow) *appsv1.Deployment {
 	size := int32(1)
-	labels := labels(instance, "backend")
+	labels := r.childLabels(instance, "backend")
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      instance.Name,
-			Namespace: instance.Namespace,
+			Name:        instance.Name,
+			Namespace:   instance.Namespace,
+			Labels:      labels,
+			Annotations: r.childAnnotations(instance),
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: &size,
 			Selector: &metav1.LabelSelector{
-				MatchLabels: labels,
+				MatchLabels: selectorLabels(instance),
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: labels,
+					Labels:      labels,
+					Annotations: r.childAnnotations(instance),
 				},
 				Spec: corev1.PodSpec{
+					ServiceAccountName:           serviceAccountName(instance),
+					AutomountServiceAccountToken: automountToken(instance),
 					Containers: []corev1.Container{{
-						Image:           "ghcr.io/vsoch/lolcow-operator:latest",
+						Image:           r.image(instance),
 						ImagePullPolicy: corev1.PullAlways,
 						Name:            instance.Name,
-						Command:         []string{"/bin/bash", "/entrypoint.sh", instance.Spec.Greeting},
+						Command:         []string{"/bin/bash", "/entrypoint.sh", r.greeting(instance)},
+						Env: []corev1.EnvVar{{
+							Name:  "PORT",
+							Value: strconv.Itoa(int(containerPort(instance))),
+						}},
 						Ports: []corev1.ContainerPort{{
-							ContainerPort: 8080,
+							ContainerPort: containerPort(instance),
 							Name:          "lolcow",
+							Protocol:      corev1.ProtocolTCP,
 						}},
 					}},
 				},
 			},
 		},
 	}
-	// Set Lolcow instance as the owner and controller
-	ctrl.SetControllerReference(instance, deployment, r.Scheme)
+	if instance.Spec.GreetingFrom != nil {
+		container := &deployment.Spec.Template.Spec.Containers[0]
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:      greetingEnv,
+			ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: instance.Spec.GreetingFrom.DeepCopy()},
+		})
+	}
+
+	// We don't touch accounts we don't own, so pull secrets go on the pod
+	if instance.Spec.ServiceAccountName != "" {
+		deployment.Spec.Template.Spec.ImagePullSecrets = instance.Spec.ImagePullSecrets
+	}
+	r.markPropagated(instance, &deployment.ObjectMeta)
 	return deployment
 }
+
+// deploymentComponent runs the lolcow, and keeps the greeting, service
+// account, ports and labels of the pods up to date
+func (r *LolcowReconciler) deploymentComponent() component {
+	return component{
+		name:       "deployment",
+		condition:  api.ConditionDeploymentReady,
+		object:     &appsv1.Deployment{},
+		predicates: []predicate.Predicate{deploymentChanged},
+		desired: func(state *lolcowState) LolcowResources {
+			return r.createDeployment(state.instance)
+		},
+		mutate: mutateDeployment,
+		ready: func(state *lolcowState, object LolcowResources) phaseResult {
+			return deploymentReady(object.(*appsv1.Deployment))
+		},
+	}
+}
+
+// mutateDeployment copies what we own onto an existing deployment
+// The selector is immutable, and we leave it (and any defaults) alone.
+func mutateDeployment(ctx context.Context, state *lolcowState, existing, desired LolcowResources) error {
+	log := logctrl.FromContext(ctx)
+	d := existing.(*appsv1.Deployment)
+	want := desired.(*appsv1.Deployment)
+
+	// The third / last entry in the command is the greeting
+	// /bin/bash /entrypoint.sh <greeting>
+	container := &d.Spec.Template.Spec.Containers[0]
+	wantContainer := want.Spec.Template.Spec.Containers[0]
+	if len(container.Command) < 3 || container.Command[2] != wantContainer.Command[2] {
+		log.Info("👋️ New Greeting! 👋️", "greeting", wantContainer.Command[2])
+	} else {
+		log.V(1).Info("👋️ No Change to Greeting! 👋️", "greeting", wantContainer.Command[2])
+	}
+	container.Command = wantContainer.Command
+	container.Image = wantContainer.Image
+	container.Ports = wantContainer.Ports
+	container.Env = wantContainer.Env
+
+	// Switch the pods to another service account, if needed
+	pod := &d.Spec.Template.Spec
+	if pod.ServiceAccountName != want.Spec.Template.Spec.ServiceAccountName {
+		pod.DeprecatedServiceAccount = ""
+	}
+	pod.ServiceAccountName = want.Spec.Template.Spec.ServiceAccountName
+	pod.AutomountServiceAccountToken = want.Spec.Template.Spec.AutomountServiceAccountToken
+	pod.ImagePullSecrets = want.Spec.Template.Spec.ImagePullSecrets
+
+	// We only remove the labels we put there. Deployments made before the
+	// recommended labels keep selecting on (and labeling pods with) the old ones.
+	// The pod template gets what the deployment does.
+	previous := propagatedKeys(d.ObjectMeta)
+	mergeMetadata(&d.ObjectMeta, want.ObjectMeta, previous)
+	mergeMetadata(&d.Spec.Template.ObjectMeta, want.Spec.Template.ObjectMeta, previous)
+	return nil
+}
+
+// deploymentReady is true when the latest pods are all rolled out and available
+func deploymentReady(d *appsv1.Deployment) phaseResult {
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	if d.Status.ObservedGeneration < d.Generation ||
+		d.Status.UpdatedReplicas < replicas || d.Status.AvailableReplicas < replicas {
+		return phaseResult{
+			reason:  reasonProgressing,
+			message: fmt.Sprintf("%d/%d replicas of %s are updated and available", d.Status.AvailableReplicas, replicas, d.Name),
+		}
+	}
+	return phaseResult{ready: true, reason: reasonReady, message: "Deployment " + d.Name + " is available"}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+// Event reasons. These are part of our API: alerting matches on them,
+// so please don't rename them.
+const (
+	EventCreated         = "Created"
+	EventGreetingChanged = "GreetingChanged"
+	EventPortChanged     = "PortChanged"
+	EventUpdated         = "Updated"
+	EventMetadataUpdated = "MetadataUpdated"
+	EventDriftCorrected  = "DriftCorrected"
+	EventDeleted         = "Deleted"
+	EventDeleting        = "Deleting"
+	EventGetFailed       = "GetFailed"
+	EventCreateFailed    = "CreateFailed"
+	EventUpdateFailed    = "UpdateFailed"
+	EventDeleteFailed    = "DeleteFailed"
+	EventStatusFailed    = "StatusUpdateFailed"
+	EventOwnershipFailed = "OwnershipConflict"
+	EventPaused          = "Paused"
+	EventResumed         = "Resumed"
+)
+
+// event records a Normal event on the Lolcow, if we have a recorder
+func (r *LolcowReconciler) event(instance *api.Lolcow, reason, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(instance, corev1.EventTypeNormal, reason, message)
+	}
+}
+
+// warning records a Warning event on the Lolcow, if we have a recorder
+func (r *LolcowReconciler) warning(instance *api.Lolcow, reason, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(instance, corev1.EventTypeWarning, reason, message)
+	}
+}
+
+// updateReason works out why we had to update a child, from the paths of
+// the changes. Labels and annotations alone (e.g., propagated from the
+// Lolcow, which doesn't bump its generation) are their own reason. If the
+// child's component already applied this generation of the Lolcow, someone
+// edited the child and we put it back (drift). Otherwise it's the greeting,
+// the ports, or other.
+func updateReason(instance *api.Lolcow, condition string, changes []string) string {
+	paths := make([]string, len(changes))
+	metadataOnly := true
+	for i, change := range changes {
+		paths[i] = strings.SplitN(change, ": ", 2)[0]
+		metadataOnly = metadataOnly && isMetadataPath(paths[i])
+	}
+	if metadataOnly {
+		return EventMetadataUpdated
+	}
+	if applied := appliedGeneration(instance, condition); applied != 0 && applied == instance.Generation {
+		return EventDriftCorrected
+	}
+	for _, path := range paths {
+		if !isMetadataPath(path) && hasField(path, func(field string) bool { return field == "command" }) {
+			return EventGreetingChanged
+		}
+	}
+	for _, path := range paths {
+		if !isMetadataPath(path) && hasField(path, isPortField) {
+			return EventPortChanged
+		}
+	}
+	return EventUpdated
+}
+
+// appliedGeneration is the last Lolcow generation a component applied
+// without an error, from its condition. It can be ahead of the observed
+// generation, which waits for every component.
+func appliedGeneration(instance *api.Lolcow, condition string) int64 {
+	if c := meta.FindStatusCondition(instance.Status.Conditions, condition); c != nil {
+		return c.ObservedGeneration
+	}
+	return 0
+}
+
+// isMetadataPath is true for the metadata of a child or its pod template.
+// Label and annotation keys can have dots, so nothing under them is a field.
+func isMetadataPath(path string) bool {
+	return strings.HasPrefix(path, "metadata.") || strings.HasPrefix(path, "spec.template.metadata.")
+}
+
+// hasField is true if any field of a path like spec.ports[0].port matches
+func hasField(path string, match func(string) bool) bool {
+	for _, field := range strings.Split(path, ".") {
+		if i := strings.Index(field, "["); i >= 0 {
+			field = field[:i]
+		}
+		if match(field) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPortField matches ports, port, nodePort, containerPort, targetPort, ...
+func isPortField(field string) bool {
+	field = strings.ToLower(field)
+	return field == "ports" || strings.HasSuffix(field, "port")
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+var _ = Describe("Events", func() {
+	DescribeTable("updateReason",
+		func(applied int64, changes []string, reason string) {
+			lolcow := newFakeLolcow(api.LolcowSpec{})
+			lolcow.Generation = 2
+			lolcow.Status.Conditions = []metav1.Condition{{Type: api.ConditionServiceReady, ObservedGeneration: applied}}
+			Expect(updateReason(lolcow, api.ConditionServiceReady, changes)).To(Equal(reason))
+		},
+		Entry("a new greeting", int64(1),
+			[]string{`spec.template.spec.containers[0].command[2]: "moo" -> "baa"`}, EventGreetingChanged),
+		Entry("a new greeting and port", int64(1), []string{
+			`spec.template.spec.containers[0].command[2]: "moo" -> "baa"`,
+			`spec.template.spec.containers[0].ports[0].containerPort: 80 -> 8080`,
+		}, EventGreetingChanged),
+		Entry("a service port", int64(1), []string{`spec.ports[0].port: 80 -> 8080`}, EventPortChanged),
+		Entry("a node port", int64(1), []string{`spec.ports[0].nodePort: 30080 -> 30090`}, EventPortChanged),
+		Entry("the ports of a network policy", int64(1),
+			[]string{`spec.ingress[0].ports: [{"port":80}] -> [{"port":8080}]`}, EventPortChanged),
+		Entry("a value that mentions a port", int64(1),
+			[]string{`spec.template.spec.serviceAccountName: "port" -> "command"`}, EventUpdated),
+		Entry("another field", int64(1),
+			[]string{`spec.template.spec.serviceAccountName: "default" -> "hello-world"`}, EventUpdated),
+		Entry("labels and annotations, however they're named", int64(1), []string{
+			`metadata.labels.team.example.com/port: <none> -> "80"`,
+			`spec.template.metadata.annotations.example.com/command: <none> -> "moo"`,
+		}, EventMetadataUpdated),
+		Entry("labels and annotations, without a new generation", int64(2),
+			[]string{`metadata.labels.team: "cows" -> "bulls"`}, EventMetadataUpdated),
+		Entry("labels and a port", int64(1), []string{
+			`metadata.labels.team.example.com/command: <none> -> "moo"`,
+			`spec.ports[0].port: 80 -> 8080`,
+		}, EventPortChanged),
+		Entry("a port, without a new generation", int64(2), []string{`spec.ports[0].port: 8080 -> 80`}, EventDriftCorrected),
+		Entry("a port, for a new component", int64(0), []string{`spec.ports[0].port: 8080 -> 80`}, EventPortChanged),
+	)
+
+	It("corrects drift after a reconcile that failed elsewhere", func() {
+		lolcow := newFakeLolcow(api.LolcowSpec{Greeting: "Moo"})
+		lolcow.Generation = 1
+
+		// Someone else has the Service, so every reconcile fails, and the
+		// lolcow's observed generation stays behind
+		isController := true
+		service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{
+			Name:      lolcow.Name,
+			Namespace: lolcow.Namespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       "someone-else",
+				UID:        "0f6e7c3a-1b2d-4e5f-8a9b-0c1d2e3f4a5b",
+				Controller: &isController,
+			}},
+		}}
+		r, c, recorder := newFakeReconciler([]client.Object{lolcow, service})
+		result, err := reconcileFake(r, lolcow)
+		Expect(err).To(HaveOccurred())
+		Expect(result.Status.ObservedGeneration).To(BeZero())
+		fakeEvents(recorder)
+
+		deployment := &appsv1.Deployment{}
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(lolcow), deployment)).To(Succeed())
+		deployment.Spec.Template.Spec.Containers[0].Command = []string{"/bin/bash", "/entrypoint.sh", "Baa"}
+		Expect(c.Update(context.Background(), deployment)).To(Succeed())
+
+		result, err = reconcileFake(r, lolcow)
+		Expect(err).To(HaveOccurred())
+		Expect(fakeEvents(recorder)).To(ContainElement(HavePrefix("Normal " + EventDriftCorrected + " Updated Deployment")))
+		condition := meta.FindStatusCondition(result.Status.Conditions, api.ConditionServiceReady)
+		Expect(condition.Reason).To(Equal("ReconcileError"))
+		Expect(condition.ObservedGeneration).To(BeZero())
+	})
+
+	Context("a lolcow being deleted", func() {
+		It("is left alone, with an event", func() {
+			lolcow := newFakeLolcow(api.LolcowSpec{})
+			now := metav1.Now()
+			lolcow.DeletionTimestamp = &now
+			lolcow.Finalizers = []string{metav1.FinalizerDeleteDependents}
+			r, c, recorder := newFakeReconciler([]client.Object{lolcow})
+			_, err := reconcileFake(r, lolcow)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeEvents(recorder)).To(Equal([]string{"Normal " + EventDeleting + " Lolcow is being deleted, its resources go with it"}))
+			err = c.Get(context.Background(), client.ObjectKeyFromObject(lolcow), &appsv1.Deployment{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+})
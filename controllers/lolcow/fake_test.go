@@ -0,0 +1,82 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+// The specs that only need reads and writes to go through run the
+// reconciler against controller-runtime's fake client. The ones about
+// validation and garbage collection need the real API server (envtest).
+
+// newFakeLolcow is a lolcow as the API server would hand it to us
+func newFakeLolcow(spec api.LolcowSpec) *api.Lolcow {
+	return &api.Lolcow{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "hello-world",
+			Namespace:  "default",
+			UID:        "6b1a3c2e-0d6f-4c1e-9a57-7d0e0c9a1f00",
+			Generation: 1,
+		},
+		Spec: spec,
+	}
+}
+
+// newFakeReconciler is a reconciler over a fake client holding objects
+func newFakeReconciler(objects []client.Object, opts ...Option) (*LolcowReconciler, client.Client, *record.FakeRecorder) {
+	scheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	Expect(api.AddToScheme(scheme)).To(Succeed())
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	recorder := record.NewFakeRecorder(100)
+	opts = append([]Option{WithRecorder(recorder)}, opts...)
+	return NewLolcowReconciler(c, scheme, opts...), c, recorder
+}
+
+// reconcileFake reconciles a lolcow in the fake client, and returns it as it was left
+func reconcileFake(r *LolcowReconciler, lolcow *api.Lolcow) (*api.Lolcow, error) {
+	ctx := context.Background()
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(lolcow)})
+	result := &api.Lolcow{}
+	Expect(r.Get(ctx, client.ObjectKeyFromObject(lolcow), result)).To(Succeed())
+	return result, err
+}
+
+// fakeEvents drains the events recorded so far, as "Type Reason Message"
+func fakeEvents(recorder *record.FakeRecorder) []string {
+	events := []string{}
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
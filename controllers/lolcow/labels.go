@@ -0,0 +1,212 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+// Recommended labels, see
+// https://kubernetes.io/docs/concepts/overview/working-with-objects/common-labels/
+const (
+	labelName      = "app.kubernetes.io/name"
+	labelInstance  = "app.kubernetes.io/instance"
+	labelComponent = "app.kubernetes.io/component"
+	labelManagedBy = "app.kubernetes.io/managed-by"
+	labelVersion   = "app.kubernetes.io/version"
+
+	managedBy = "lolcow-operator"
+)
+
+// The Lolcow labels and annotations copied to a child are listed (comma
+// separated) on the child, so the ones removed from the Lolcow can be
+// removed from the child too
+const (
+	propagatedLabelsAnnotation      = "lolcow.my.domain/propagated-labels"
+	propagatedAnnotationsAnnotation = "lolcow.my.domain/propagated-annotations"
+)
+
+// selectorLabels are the (immutable) subset of labels used to select pods
+func selectorLabels(v *api.Lolcow) map[string]string {
+	return map[string]string{
+		labelName:     "lolcow",
+		labelInstance: v.Name,
+	}
+}
+
+// labels fetches and sets labels
+func (r *LolcowReconciler) labels(v *api.Lolcow, tier string) map[string]string {
+	labels := selectorLabels(v)
+	labels[labelComponent] = tier
+	labels[labelManagedBy] = managedBy
+	if version := imageVersion(r.image(v)); version != "" {
+		labels[labelVersion] = version
+	}
+	return labels
+}
+
+// imageVersion is the tag of an image, "latest" if there isn't one, or
+// empty if it can't be a label value
+func imageVersion(image string) string {
+
+	// A digest doesn't fit in a label value, so only a tag next to it counts
+	image, digest := splitDigest(image)
+	slash := strings.LastIndex(image, "/")
+	colon := strings.LastIndex(image, ":")
+	if colon <= slash {
+		if digest {
+			return ""
+		}
+		return "latest"
+	}
+
+	// Tags can be up to 128 characters, and start with an underscore or end
+	// with a dot or dash, which label values can't
+	version := image[colon+1:]
+	if len(version) > validation.LabelValueMaxLength {
+		version = version[:validation.LabelValueMaxLength]
+	}
+	version = strings.Trim(version, "_.-")
+	if len(validation.IsValidLabelValue(version)) > 0 {
+		return ""
+	}
+	return version
+}
+
+// splitDigest drops the digest of an image, and says if there was one
+func splitDigest(image string) (string, bool) {
+	parts := strings.SplitN(image, "@", 2)
+	return parts[0], len(parts) == 2
+}
+
+// childLabels are the labels for a child object, including any the user
+// asked to propagate from the Lolcow itself. Ours win on conflict.
+func (r *LolcowReconciler) childLabels(v *api.Lolcow, tier string) map[string]string {
+	result := propagate(v.Labels, r.LabelPrefixes)
+	for key, value := range r.labels(v, tier) {
+		result[key] = value
+	}
+	return result
+}
+
+// childAnnotations are the Lolcow annotations allowed to propagate
+func (r *LolcowReconciler) childAnnotations(v *api.Lolcow) map[string]string {
+	return propagate(v.Annotations, r.AnnotationPrefixes)
+}
+
+// markPropagated lists on a child the labels and annotations it gets from
+// the Lolcow (see mergeMetadata)
+func (r *LolcowReconciler) markPropagated(v *api.Lolcow, child *metav1.ObjectMeta) {
+	for annotation, keys := range map[string]map[string]string{
+		propagatedLabelsAnnotation:      propagate(v.Labels, r.LabelPrefixes),
+		propagatedAnnotationsAnnotation: propagate(v.Annotations, r.AnnotationPrefixes),
+	} {
+		if len(keys) == 0 {
+			continue
+		}
+		if child.Annotations == nil {
+			child.Annotations = map[string]string{}
+		}
+		child.Annotations[annotation] = joinKeys(keys)
+	}
+}
+
+// propagate returns the entries with a key matching one of the prefixes
+func propagate(from map[string]string, prefixes []string) map[string]string {
+	matched := map[string]string{}
+	for key, value := range from {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				matched[key] = value
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// propagated are the keys a child was given the last time, from markPropagated
+type propagated struct {
+	labels      []string
+	annotations []string
+}
+
+func propagatedKeys(child metav1.ObjectMeta) propagated {
+	return propagated{
+		labels:      splitKeys(child.Annotations[propagatedLabelsAnnotation]),
+		annotations: splitKeys(child.Annotations[propagatedAnnotationsAnnotation]),
+	}
+}
+
+// mergeMetadata brings the labels and annotations of an existing child up
+// to date. Ours and the propagated ones are added (or updated), and the ones
+// we put there before but don't want anymore (previous, and the version
+// label) are removed. Anything else, e.g., another controller's, is left alone.
+func mergeMetadata(existing *metav1.ObjectMeta, desired metav1.ObjectMeta, previous propagated) {
+	mergeInto(&existing.Labels, desired.Labels)
+	mergeInto(&existing.Annotations, desired.Annotations)
+	removeUnwanted(existing.Labels, append(previous.labels, labelVersion), desired.Labels)
+	removeUnwanted(existing.Annotations,
+		append(previous.annotations, propagatedLabelsAnnotation, propagatedAnnotationsAnnotation), desired.Annotations)
+}
+
+// removeUnwanted deletes the keys that aren't desired
+func removeUnwanted(existing map[string]string, keys []string, desired map[string]string) {
+	for _, key := range keys {
+		if _, ok := desired[key]; !ok {
+			delete(existing, key)
+		}
+	}
+}
+
+func joinKeys(entries map[string]string) string {
+	keys := []string{}
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+func splitKeys(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// mergeInto adds (or updates) the desired entries in existing, leaving
+// anything we don't manage alone. It returns true if something changed.
+func mergeInto(existing *map[string]string, desired map[string]string) bool {
+	changed := false
+	if *existing == nil && len(desired) > 0 {
+		*existing = map[string]string{}
+	}
+	for key, value := range desired {
+		if current, ok := (*existing)[key]; !ok || current != value {
+			(*existing)[key] = value
+			changed = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+var _ = Describe("Labels", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	DescribeTable("imageVersion",
+		func(image, version string) {
+			Expect(imageVersion(image)).To(Equal(version))
+		},
+		Entry("a tag", "ghcr.io/vsoch/lolcow-operator:0.1.2", "0.1.2"),
+		Entry("no tag", "ghcr.io/vsoch/lolcow-operator", "latest"),
+		Entry("a registry port, but no tag", "localhost:5000/lolcow", "latest"),
+		Entry("a registry port and a tag", "localhost:5000/lolcow:v2", "v2"),
+		Entry("only a digest", "lolcow@sha256:"+strings.Repeat("a", 64), ""),
+		Entry("a tag and a digest", "lolcow:v2@sha256:"+strings.Repeat("a", 64), "v2"),
+		Entry("a tag too long", "lolcow:"+strings.Repeat("a", 100), strings.Repeat("a", 63)),
+		Entry("a tag too long, cut at a dash", "lolcow:"+strings.Repeat("a", 62)+"-b", strings.Repeat("a", 62)),
+		Entry("a tag starting with an underscore", "lolcow:_build.7", "build.7"),
+		Entry("a tag ending with a dot", "lolcow:v1.", "v1"),
+		Entry("nothing left of a tag", "lolcow:___", ""),
+	)
+
+	It("leaves out the version of an image without one", func() {
+		r := NewLolcowReconciler(nil, nil)
+		lolcow := newFakeLolcow(api.LolcowSpec{Image: "lolcow@sha256:" + strings.Repeat("a", 64)})
+		Expect(r.labels(lolcow, "backend")).NotTo(HaveKey(labelVersion))
+	})
+
+	Context("propagated from the lolcow", func() {
+		var (
+			lolcow   *api.Lolcow
+			r        *LolcowReconciler
+			c        client.Client
+			recorder *record.FakeRecorder
+		)
+
+		BeforeEach(func() {
+			lolcow = newFakeLolcow(api.LolcowSpec{})
+			lolcow.Labels = map[string]string{
+				"team.example.com/owner": "cows",
+				"team.example.com/cost":  "moo",
+				"private":                "yes",
+				labelName:                "not-lolcow",
+			}
+			lolcow.Annotations = map[string]string{"team.example.com/docs": "https://example.com"}
+			r, c, recorder = newFakeReconciler([]client.Object{lolcow},
+				WithPropagation([]string{"team.example.com/", labelName}, []string{"team.example.com/"}))
+			_, err := reconcileFake(r, lolcow)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		children := func() (*appsv1.Deployment, *corev1.Service) {
+			deployment := &appsv1.Deployment{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(lolcow), deployment)).To(Succeed())
+			service := &corev1.Service{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(lolcow), service)).To(Succeed())
+			return deployment, service
+		}
+
+		It("copies the matching labels and annotations, and ours win", func() {
+			deployment, service := children()
+			for _, labels := range []map[string]string{deployment.Labels, deployment.Spec.Template.Labels, service.Labels} {
+				Expect(labels).To(HaveKeyWithValue("team.example.com/owner", "cows"))
+				Expect(labels).To(HaveKeyWithValue(labelName, "lolcow"))
+				Expect(labels).NotTo(HaveKey("private"))
+			}
+			for _, annotations := range []map[string]string{deployment.Annotations, deployment.Spec.Template.Annotations, service.Annotations} {
+				Expect(annotations).To(HaveKeyWithValue("team.example.com/docs", "https://example.com"))
+			}
+
+			// The pods aren't told what was propagated
+			Expect(deployment.Annotations).To(HaveKeyWithValue(propagatedLabelsAnnotation, labelName+",team.example.com/cost,team.example.com/owner"))
+			Expect(deployment.Annotations).To(HaveKeyWithValue(propagatedAnnotationsAnnotation, "team.example.com/docs"))
+			Expect(deployment.Spec.Template.Annotations).NotTo(HaveKey(propagatedLabelsAnnotation))
+		})
+
+		It("removes what the lolcow doesn't have anymore, and nothing else", func() {
+			deployment, service := children()
+			deployment.Labels["someone-else"] = "theirs"
+			Expect(c.Update(ctx, deployment)).To(Succeed())
+
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(lolcow), lolcow)).To(Succeed())
+			delete(lolcow.Labels, "team.example.com/cost")
+			lolcow.Labels["team.example.com/owner"] = "bulls"
+			lolcow.Annotations = nil
+			Expect(c.Update(ctx, lolcow)).To(Succeed())
+			fakeEvents(recorder)
+			_, err := reconcileFake(r, lolcow)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeEvents(recorder)).To(ConsistOf(
+				HavePrefix("Normal MetadataUpdated Updated Deployment hello-world:"),
+				HavePrefix("Normal MetadataUpdated Updated Service hello-world:"),
+			))
+
+			deployment, service = children()
+			for _, labels := range []map[string]string{deployment.Labels, deployment.Spec.Template.Labels, service.Labels} {
+				Expect(labels).NotTo(HaveKey("team.example.com/cost"))
+				Expect(labels).To(HaveKeyWithValue("team.example.com/owner", "bulls"))
+				Expect(labels).To(HaveKeyWithValue(labelName, "lolcow"))
+			}
+			Expect(deployment.Labels).To(HaveKeyWithValue("someone-else", "theirs"))
+			for _, annotations := range []map[string]string{deployment.Annotations, deployment.Spec.Template.Annotations, service.Annotations} {
+				Expect(annotations).NotTo(HaveKey("team.example.com/docs"))
+				Expect(annotations).NotTo(HaveKey(propagatedAnnotationsAnnotation))
+			}
+			Expect(deployment.Annotations).To(HaveKeyWithValue(propagatedLabelsAnnotation, labelName+",team.example.com/owner"))
+		})
+
+		It("removes the version label when the image doesn't have one", func() {
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(lolcow), lolcow)).To(Succeed())
+			lolcow.Spec.Image = "lolcow@sha256:" + strings.Repeat("a", 64)
+			Expect(c.Update(ctx, lolcow)).To(Succeed())
+			_, err := reconcileFake(r, lolcow)
+			Expect(err).NotTo(HaveOccurred())
+
+			deployment, service := children()
+			Expect(deployment.Labels).NotTo(HaveKey(labelVersion))
+			Expect(deployment.Spec.Template.Labels).NotTo(HaveKey(labelVersion))
+			Expect(service.Labels).NotTo(HaveKey(labelVersion))
+		})
+	})
+
+	Context("a deployment from before the recommended labels", func() {
+		old := map[string]string{"app": "hello-world"}
+
+		It("keeps its selector and old labels, and the service follows it", func() {
+			lolcow := newFakeLolcow(api.LolcowSpec{})
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "hello-world", Namespace: "default", Labels: old},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: old},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: old},
+						Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "hello-world"}}},
+					},
+				},
+			}
+			r, c, _ := newFakeReconciler([]client.Object{lolcow, deployment})
+			_, err := reconcileFake(r, lolcow)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(lolcow), deployment)).To(Succeed())
+			Expect(deployment.Spec.Selector.MatchLabels).To(Equal(old))
+			for _, labels := range []map[string]string{deployment.Labels, deployment.Spec.Template.Labels} {
+				Expect(labels).To(HaveKeyWithValue("app", "hello-world"))
+				Expect(labels).To(HaveKeyWithValue(labelName, "lolcow"))
+				Expect(labels).To(HaveKeyWithValue(labelManagedBy, managedBy))
+			}
+
+			service := &corev1.Service{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(lolcow), service)).To(Succeed())
+			Expect(service.Spec.Selector).To(Equal(old))
+		})
+	})
+})
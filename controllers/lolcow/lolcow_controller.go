@@ -14,26 +14,33 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-// TODO vsoch I like this design better
-// https://github.com/GoogleCloudPlatform/airflow-operator/blob/master/pkg/controller/controller.go
-
 package controllers
 
 import (
 	"context"
+	"time"
 
-	appsv1 "k8s.io/api/apps/v1"
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlbuilder "sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	logctrl "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+	"vsoch/lolcow-operator/pkg/config"
+	"vsoch/lolcow-operator/pkg/health"
+	"vsoch/lolcow-operator/pkg/logging"
 	"vsoch/lolcow-operator/pkg/lolcow"
+	"vsoch/lolcow-operator/pkg/metrics"
+	"vsoch/lolcow-operator/pkg/tracing"
 )
 
 // LolcowReconciler reconciles a Lolcow object
@@ -43,32 +50,57 @@ type LolcowReconciler struct {
 
 	// An added "greeter" to hold the greeting
 	Greeter *lolcow.Greeter
-}
 
-// GenericResource have both meta and runtime interfaces
-type LolcowResources interface {
-	metav1.Object
-	runtime.Object
+	// Recorder emits the events you see in kubectl describe lolcow
+	Recorder record.EventRecorder
+
+	// Lolcow labels and annotations with these prefixes are copied to
+	// the Deployment, pod template and Service
+	LabelPrefixes      []string
+	AnnotationPrefixes []string
+
+	// DefaultImage serves the greeting, and DefaultServiceType exposes it
+	// Empty uses the defaults in pkg/config.
+	DefaultImage       string
+	DefaultServiceType corev1.ServiceType
+
+	// MaxConcurrentReconciles is how many lolcows are reconciled at once
+	MaxConcurrentReconciles int
+
+	// RateLimiter paces retries of failed lolcows, nil is controller-runtime's default
+	RateLimiter workqueue.RateLimiter
+
+	// FeatureGates that were set, the rest have their default
+	FeatureGates map[string]bool
+
+	// Watchdog, if any, is told about every reconcile (see pkg/health)
+	Watchdog *health.Watchdog
 }
 
 // NewLolcowReconciler returns the Lolcow Reconciler to the core controller
-func NewLolcowReconciler(client client.Client, scheme *runtime.Scheme, greeter *lolcow.Greeter) *LolcowReconciler {
-
-	// TODO Could this have options (ops) instead of hard coding the specific params (e.g., Greeting)
-	// https://github.com/kubernetes-sigs/kueue/blob/47ec7d6033ae7527b5495ed432ae4390fc052523/pkg/controller/workload/job/job_controller.go#L78
-	return &LolcowReconciler{
+// Anything not given as an option has its default (see pkg/config).
+func NewLolcowReconciler(client client.Client, scheme *runtime.Scheme, opts ...Option) *LolcowReconciler {
+	r := &LolcowReconciler{
 		Client:  client,
 		Scheme:  scheme,
-		Greeter: greeter,
+		Greeter: lolcow.NewGreeter(config.DefaultGreeting),
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
+// The manager checks it has these at startup, see permissions.go
 //+kubebuilder:rbac:groups=my.domain,resources=lolcows,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=my.domain,resources=lolcows/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=my.domain,resources=lolcows/finalizers,verbs=update
-//+kubebuilder:rbac:groups=my.domain,resources=deployments,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups=my.domain,resources=pods,verbs=get;list;watch;create;
-//+kubebuilder:rbac:groups=my.domain,resources=services,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -77,133 +109,118 @@ func NewLolcowReconciler(client client.Client, scheme *runtime.Scheme, greeter *
 // 3. Is it different? Perform operations to reflect new user preferences
 // 4. Is it the same? No changes needed.
 //
+// Each owned resource is a component (see component.go), and all of them
+// run every time, so a greeting change doesn't postpone the port check.
+// We don't ask to be requeued: changes to the owned resources come back
+// to us through the watches in SetupWithManager.
+//
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.12.1/pkg/reconcile
-func (r *LolcowReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *LolcowReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 
 	// The Lolcow instance!
 	var instance api.Lolcow
 
-	// Prepare a logger to communicate to the developer user
-	// Note that we could attach a named logger to the reconciler object,
-	// and that might be a better approach for organization or state
-	// https://github.com/kubernetes-sigs/kueue/blob/main/pkg/controller/core/queue_controller.go#L50
-	log := logctrl.FromContext(ctx).WithValues("Lolcow", req.NamespacedName)
+	// Every reconcile is a trace, and its id goes into the logs
+	// The logger already has the lolcow and namespace (see SetupWithManager)
+	ctx, span := tracing.Start(ctx, "Reconcile",
+		attribute.String("lolcow.namespace", req.Namespace),
+		attribute.String("lolcow.name", req.Name),
+	)
+	log := tracing.WithTraceID(ctx, logctrl.FromContext(ctx))
+	ctx = logctrl.IntoContext(ctx, log)
+	defer func() { tracing.End(span, err) }()
 
 	// Keep developed informed what is going on.
-	log.Info("⚡️ Event received! ⚡️")
-	log.Info("Request: ", "req", req)
+	log.V(1).Info("⚡️ Event received! ⚡️")
 
-	err := r.Get(ctx, req.NamespacedName, &instance)
+	err = r.Get(ctx, req.NamespacedName, &instance)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			log.Info("Lolcow resource not found. Ignoring since object must be deleted.")
 			return ctrl.Result{}, nil
 		}
-		log.Info("Failed to get Lolcow resource. Re-running reconcile.")
+		log.Error(err, "Failed to get Lolcow resource. Re-running reconcile.")
 		return ctrl.Result{}, err
 	}
-	log.Info("🥑️ Found instance 🥑️", instance.Spec.Greeting, instance.Spec.Port)
-
-	// Do we have a current lolcow instance deployed?
-	existingD := &appsv1.Deployment{}
-	err = r.Get(ctx, types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, existingD)
-	if err != nil {
 
-		// Case 1: not found yet, check if deployment needs deletion
-		if errors.IsNotFound(err) {
-			dep := r.createDeployment(&instance)
-			log.Info("✨ Creating a new Deployment ✨", "Namespace", dep.Namespace, "Deployment.Name", dep.Name)
-			err = r.Create(ctx, dep)
-			if err != nil {
-				log.Error(err, "❌ Failed to create new Deployment", "Namespace", dep.Namespace, "Name", dep.Name)
-				return ctrl.Result{}, err
-			}
-			// Deployment created successfully - return and requeue
-			return ctrl.Result{Requeue: true}, nil
-		} else if err != nil {
-			log.Error(err, "Failed to get Deployment")
-			return ctrl.Result{}, err
-		}
+	// lolcow.my.domain/log-level: debug turns on debug logs for this lolcow only
+	if level, ok := instance.Annotations[api.LogLevelAnnotation]; ok {
+		log = logging.WithLevel(log, level)
+		ctx = logctrl.IntoContext(ctx, log)
 	}
-
-	// If we get down here, we have a current instance deployed (not freshly created)
-	greeting := instance.Spec.Greeting
-
-	// The third / last entry in the command is the greeting
-	existingGreeting := existingD.Spec.Template.Spec.Containers[0].Command[2]
-	log.Info("Requested vs existing greeting : ", greeting, existingGreeting)
-
-	// Do we need to update the greeting?
-	if existingGreeting != greeting {
-
-		log.Info("👋️ New Greeting! 👋️: ", greeting, existingGreeting)
-
-		// /bin/bash /entrypoint.sh <greeting>
-		existingD.Spec.Template.Spec.Containers[0].Command[2] = greeting
-		err = r.Update(ctx, existingD)
-		if err != nil {
-			log.Error(err, "Failed to update Deployment", "Namespace", existingD.Namespace, "Name", existingD.Name)
-			return ctrl.Result{}, err
-		}
-		// Deployment updated - return and requeue
-		return ctrl.Result{Requeue: true}, nil
-	} else {
-		log.Info("👋️ No Change to Greeting! 👋️: ", greeting, existingGreeting)
+	log.V(1).Info("🥑️ Found instance 🥑️", "greeting", instance.Spec.Greeting, "port", instance.Spec.Port, "generation", instance.Generation)
+
+	// We don't add a finalizer: the children are garbage collected with their
+	// owner. A lolcow is only seen here while someone else's finalizer (e.g.,
+	// foreground deletion) holds it, and we mustn't recreate what's going away.
+	if !instance.DeletionTimestamp.IsZero() {
+		log.V(1).Info("🧹 Lolcow is being deleted, skipping 🧹")
+		r.event(&instance, EventDeleting, "Lolcow is being deleted, its resources go with it")
+		return ctrl.Result{}, nil
 	}
 
-	// Do we have a current service deployed?
-	existingS := &corev1.Service{}
-	err = r.Get(ctx, types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, existingS)
-	if err != nil {
-
-		// Case 1: not found yet, check if deployment needs deletion
-		if errors.IsNotFound(err) {
-			service := r.createService(&instance)
-			log.Info("✨ Creating a new Service ✨", "Namespace", service.Namespace, "Name", service.Name)
-			err = r.Create(ctx, service)
-			if err != nil {
-				log.Error(err, "❌ Failed to create new Service", "Namespace", service.Namespace, "Name", service.Name)
-				return ctrl.Result{}, err
-			}
-			// Service created successfully - return and requeue
-			return ctrl.Result{Requeue: true}, nil
-		} else if err != nil {
-			log.Error(err, "Failed to get Service")
-			return ctrl.Result{}, err
-		}
-
-		// We found the service, but did the port change (and needs to be updated?)
+	// A paused lolcow keeps its resources as they are, until it's resumed
+	original := instance.Status.DeepCopy()
+	if reason, ok := paused(&instance); ok {
+		log.Info("⏸️ Lolcow is paused, skipping ⏸️", "reason", reason)
+		r.setPaused(&instance, reason)
 	} else {
 
-		var port int32 = instance.Spec.Port
-		existingPort := existingS.Spec.Ports[0].NodePort
-
-		// Do we need to update the port
-		if port != existingPort {
-			log.Info("🔁 New Port! 🔁")
-			existingS.Spec.Ports[0].NodePort = port
-			err = r.Update(ctx, existingS)
-			if err != nil {
-				log.Error(err, "Failed to update Service", "Namespace", existingS.Namespace, "Name", existingS.Name)
-				return ctrl.Result{}, err
+		// Run every component, then tell the user what we saw
+		r.clearPaused(&instance)
+		err = r.reconcileComponents(ctx, &instance)
+	}
+	if !equality.Semantic.DeepEqual(original, &instance.Status) {
+		defer metrics.ObservePhase("status", time.Now())
+		if statusErr := r.Status().Update(ctx, &instance); statusErr != nil {
+			log.Error(statusErr, "Failed to update Lolcow status")
+			r.warning(&instance, EventStatusFailed, "Failed to update status: "+statusErr.Error())
+			if err == nil {
+				err = statusErr
 			}
-			// Service updated - return and requeue
-			return ctrl.Result{Requeue: true}, nil
-		} else {
-			log.Info("🔁 No Change to Port! 🔁")
 		}
 	}
-	return ctrl.Result{}, nil
+	return ctrl.Result{}, err
+}
+
+// maxConcurrentReconciles defaults to one lolcow at a time
+func (r *LolcowReconciler) maxConcurrentReconciles() int {
+	if r.MaxConcurrentReconciles < 1 {
+		return config.DefaultMaxConcurrentReconciles
+	}
+	return r.MaxConcurrentReconciles
 }
 
 // SetupWithManager sets up the controller with the Manager.
+// We watch every kind a component owns, so changes to them requeue the Lolcow.
+// The predicates (see predicates.go) drop the changes that wouldn't change anything.
+// Each component picks its own, e.g., the Deployment's status changes on every rollout.
 func (r *LolcowReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&api.Lolcow{}).
-		Owns(&appsv1.Deployment{}).
-		Owns(&corev1.Service{}).
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&api.Lolcow{}, ctrlbuilder.WithPredicates(lolcowChanged())).
+		WithLogConstructor(func(req *reconcile.Request) logr.Logger {
+			log := mgr.GetLogger().WithValues("controller", "lolcow")
+			if req != nil {
+				log = log.WithValues(logging.KeyLolcow, req.Name, logging.KeyNamespace, req.Namespace)
+			}
+			return log
+		})
+	for _, c := range r.components() {
+		builder = builder.Owns(c.object, ctrlbuilder.WithPredicates(c.predicates...))
+	}
+	var reconciler reconcile.Reconciler = r
+	if r.Watchdog != nil {
+		reconciler = r.Watchdog.Wrap(r)
+	}
+
+	// The name is also the name of the workqueue, which the watchdog watches
+	return builder.
+		Named(ControllerName).
 		// Defaults to 1, putting here so we know it exists!
-		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
-		Complete(r)
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.maxConcurrentReconciles(),
+			RateLimiter:             r.RateLimiter,
+		}).
+		Complete(reconciler)
 }
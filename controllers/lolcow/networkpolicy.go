@@ -0,0 +1,101 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// createNetworkPolicy only allows the requested peers to reach the lolcow port
+func (r *LolcowReconciler) createNetworkPolicy(instance *api.Lolcow) *networkingv1.NetworkPolicy {
+
+	// The named container port, so this follows the deployment
+	protocol := corev1.ProtocolTCP
+	port := intstr.FromString("lolcow")
+
+	peers := []networkingv1.NetworkPolicyPeer{}
+	for _, allowed := range instance.Spec.NetworkPolicy.AllowedFrom {
+		peer := networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: allowed.NamespaceSelector,
+			PodSelector:       allowed.PodSelector,
+		}
+		if allowed.CIDR != "" {
+			peer.IPBlock = &networkingv1.IPBlock{
+				CIDR:   allowed.CIDR,
+				Except: allowed.Except,
+			}
+		}
+		peers = append(peers, peer)
+	}
+
+	// No peers means the lolcow is closed to everyone (ingress: [] denies all)
+	ingress := []networkingv1.NetworkPolicyIngressRule{}
+	if len(peers) > 0 {
+		ingress = append(ingress, networkingv1.NetworkPolicyIngressRule{
+			From:  peers,
+			Ports: []networkingv1.NetworkPolicyPort{{Protocol: &protocol, Port: &port}},
+		})
+	}
+
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      instance.Name,
+			Namespace: instance.Namespace,
+			Labels:    r.labels(instance, "backend"),
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: selectorLabels(instance),
+			},
+			Ingress:     ingress,
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+	return policy
+}
+
+// networkPolicyComponent only lets the requested peers reach the lolcow,
+// and is removed again when the lolcow stops asking for it
+func (r *LolcowReconciler) networkPolicyComponent() component {
+	return component{
+		name:      "networkpolicy",
+		condition: api.ConditionNetworkPolicyReady,
+		object:    &networkingv1.NetworkPolicy{},
+		enabled: func(state *lolcowState) bool {
+			return state.instance.Spec.NetworkPolicy != nil
+		},
+		desired: func(state *lolcowState) LolcowResources {
+			policy := r.createNetworkPolicy(state.instance)
+			policy.Spec.PodSelector.MatchLabels = state.podSelector()
+			return policy
+		},
+		mutate: func(ctx context.Context, state *lolcowState, existing, desired LolcowResources) error {
+			policy := existing.(*networkingv1.NetworkPolicy)
+			want := desired.(*networkingv1.NetworkPolicy)
+			mergeMetadata(&policy.ObjectMeta, want.ObjectMeta, propagated{})
+			policy.Spec = want.Spec
+			return nil
+		},
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+	"vsoch/lolcow-operator/pkg/config"
+)
+
+var _ = Describe("NetworkPolicy", func() {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"team": "cows"}}
+	tcp := corev1.ProtocolTCP
+	lolcowPort := intstr.FromString("lolcow")
+
+	It("only lets the peers reach the named lolcow port", func() {
+		r := NewLolcowReconciler(nil, nil)
+		policy := r.createNetworkPolicy(newFakeLolcow(api.LolcowSpec{NetworkPolicy: &api.LolcowNetworkPolicy{
+			AllowedFrom: []api.LolcowNetworkPeer{
+				{NamespaceSelector: selector, PodSelector: selector},
+				{CIDR: "10.0.0.0/16", Except: []string{"10.0.1.0/24"}},
+			},
+		}}))
+
+		Expect(policy.Name).To(Equal("hello-world"))
+		Expect(policy.Namespace).To(Equal("default"))
+		Expect(policy.Spec.PodSelector.MatchLabels).To(Equal(map[string]string{
+			labelName: "lolcow", labelInstance: "hello-world",
+		}))
+		Expect(policy.Spec.PolicyTypes).To(Equal([]networkingv1.PolicyType{networkingv1.PolicyTypeIngress}))
+		Expect(policy.Spec.Ingress).To(Equal([]networkingv1.NetworkPolicyIngressRule{{
+			From: []networkingv1.NetworkPolicyPeer{
+				{NamespaceSelector: selector, PodSelector: selector},
+				{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/16", Except: []string{"10.0.1.0/24"}}},
+			},
+			Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &lolcowPort}},
+		}}))
+	})
+
+	It("denies all ingress without peers", func() {
+		r := NewLolcowReconciler(nil, nil)
+		policy := r.createNetworkPolicy(newFakeLolcow(api.LolcowSpec{NetworkPolicy: &api.LolcowNetworkPolicy{}}))
+		Expect(policy.Spec.Ingress).NotTo(BeNil())
+		Expect(policy.Spec.Ingress).To(BeEmpty())
+		Expect(policy.Spec.PolicyTypes).To(Equal([]networkingv1.PolicyType{networkingv1.PolicyTypeIngress}))
+	})
+
+	Context("reconciled", func() {
+		var (
+			ctx    context.Context
+			lolcow *api.Lolcow
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			lolcow = newFakeLolcow(api.LolcowSpec{NetworkPolicy: &api.LolcowNetworkPolicy{
+				AllowedFrom: []api.LolcowNetworkPeer{{PodSelector: selector}},
+			}})
+		})
+
+		getPolicy := func(c client.Client) (*networkingv1.NetworkPolicy, error) {
+			policy := &networkingv1.NetworkPolicy{}
+			return policy, c.Get(ctx, client.ObjectKeyFromObject(lolcow), policy)
+		}
+
+		It("is created, owned by the lolcow, and reported", func() {
+			r, c, _ := newFakeReconciler([]client.Object{lolcow})
+			result, err := reconcileFake(r, lolcow)
+			Expect(err).NotTo(HaveOccurred())
+
+			policy, err := getPolicy(c)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metav1.IsControlledBy(policy, lolcow)).To(BeTrue())
+			Expect(result.Status.Conditions).To(ContainElement(And(
+				HaveField("Type", api.ConditionNetworkPolicyReady),
+				HaveField("Status", metav1.ConditionTrue),
+			)))
+		})
+
+		It("follows the selector of an existing deployment", func() {
+			old := map[string]string{"app": "hello-world"}
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "hello-world", Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: old},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: old},
+						Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "hello-world"}}},
+					},
+				},
+			}
+			r, c, _ := newFakeReconciler([]client.Object{lolcow, deployment})
+			_, err := reconcileFake(r, lolcow)
+			Expect(err).NotTo(HaveOccurred())
+
+			policy, err := getPolicy(c)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy.Spec.PodSelector.MatchLabels).To(Equal(old))
+		})
+
+		It("is removed when the lolcow stops asking for it", func() {
+			r, c, _ := newFakeReconciler([]client.Object{lolcow})
+			_, err := reconcileFake(r, lolcow)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(lolcow), lolcow)).To(Succeed())
+			lolcow.Spec.NetworkPolicy = nil
+			Expect(c.Update(ctx, lolcow)).To(Succeed())
+			result, err := reconcileFake(r, lolcow)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = getPolicy(c)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			Expect(result.Status.Conditions).To(ContainElement(And(
+				HaveField("Type", api.ConditionNetworkPolicyReady),
+				HaveField("Reason", reasonNotRequested),
+			)))
+		})
+
+		It("isn't made with the NetworkPolicy feature gate off", func() {
+			r, c, _ := newFakeReconciler([]client.Object{lolcow},
+				WithFeatureGates(map[string]bool{config.NetworkPolicy: false}))
+			_, err := reconcileFake(r, lolcow)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = getPolicy(c)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+})
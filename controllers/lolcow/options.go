@@ -0,0 +1,141 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+// Options for NewLolcowReconciler, in the style of
+// https://github.com/kubernetes-sigs/kueue/blob/47ec7d6033ae7527b5495ed432ae4390fc052523/pkg/controller/workload/job/job_controller.go#L78
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"vsoch/lolcow-operator/api/config/v1alpha1"
+	"vsoch/lolcow-operator/controllers/core"
+	operatorconfig "vsoch/lolcow-operator/pkg/config"
+	"vsoch/lolcow-operator/pkg/health"
+	"vsoch/lolcow-operator/pkg/lolcow"
+)
+
+// ControllerName is the name of the lolcow controller in --controllers
+const ControllerName = "lolcow"
+
+func init() {
+	core.Register(ControllerName, true, Setup)
+	core.RegisterPermissions(ControllerName, Permissions)
+}
+
+// Option configures a LolcowReconciler
+type Option func(*LolcowReconciler)
+
+// WithGreeter sets the greeter, which greets for lolcows without a greeting
+func WithGreeter(greeter *lolcow.Greeter) Option {
+	return func(r *LolcowReconciler) {
+		r.Greeter = greeter
+	}
+}
+
+// WithRecorder sets the recorder for the events on lolcows
+func WithRecorder(recorder record.EventRecorder) Option {
+	return func(r *LolcowReconciler) {
+		r.Recorder = recorder
+	}
+}
+
+// WithPropagation sets the prefixes of the Lolcow labels and annotations
+// copied to its children
+func WithPropagation(labelPrefixes, annotationPrefixes []string) Option {
+	return func(r *LolcowReconciler) {
+		r.LabelPrefixes = labelPrefixes
+		r.AnnotationPrefixes = annotationPrefixes
+	}
+}
+
+// WithDefaultImage sets the image that serves the greeting
+func WithDefaultImage(image string) Option {
+	return func(r *LolcowReconciler) {
+		r.DefaultImage = image
+	}
+}
+
+// WithDefaultServiceType sets the type of the lolcow Services
+func WithDefaultServiceType(serviceType corev1.ServiceType) Option {
+	return func(r *LolcowReconciler) {
+		r.DefaultServiceType = serviceType
+	}
+}
+
+// WithMaxConcurrentReconciles sets how many lolcows are reconciled at once
+func WithMaxConcurrentReconciles(count int) Option {
+	return func(r *LolcowReconciler) {
+		r.MaxConcurrentReconciles = count
+	}
+}
+
+// WithRateLimiter sets how failed lolcows are retried
+func WithRateLimiter(limiter workqueue.RateLimiter) Option {
+	return func(r *LolcowReconciler) {
+		r.RateLimiter = limiter
+	}
+}
+
+// WithFeatureGates sets the feature gates
+func WithFeatureGates(gates map[string]bool) Option {
+	return func(r *LolcowReconciler) {
+		r.FeatureGates = gates
+	}
+}
+
+// WithWatchdog tells the watchdog about every reconcile
+func WithWatchdog(watchdog *health.Watchdog) Option {
+	return func(r *LolcowReconciler) {
+		r.Watchdog = watchdog
+	}
+}
+
+// ConfigOptions are the options the operator config sets, for the
+// controller and for rendering lolcows offline
+func ConfigOptions(config *v1alpha1.OperatorConfig) []Option {
+	return []Option{
+		WithGreeter(lolcow.NewGreeter(config.Lolcow.DefaultGreeting)),
+		WithPropagation(config.Lolcow.PropagateLabelPrefixes, config.Lolcow.PropagateAnnotationPrefixes),
+		WithDefaultImage(config.Lolcow.DefaultImage),
+		WithDefaultServiceType(config.Lolcow.DefaultServiceType),
+		WithMaxConcurrentReconciles(config.Lolcow.MaxConcurrentReconciles),
+		WithRateLimiter(operatorconfig.NewRateLimiter(config.Lolcow.RateLimiter)),
+		WithFeatureGates(config.FeatureGates),
+	}
+}
+
+// Setup adds the lolcow controller to the manager, from the operator config
+func Setup(mgr ctrl.Manager, config *v1alpha1.OperatorConfig) error {
+	opts := append(ConfigOptions(config), WithRecorder(mgr.GetEventRecorderFor("lolcow-controller")))
+
+	// The liveness probe fails when the workqueue is stuck
+	if timeout := config.Lolcow.StuckQueueTimeout.Duration; timeout > 0 {
+		watchdog, err := health.NewWatchdog(ControllerName, timeout)
+		if err != nil {
+			return err
+		}
+		if err := mgr.AddHealthzCheck(ControllerName+"-queue", watchdog.Check); err != nil {
+			return err
+		}
+		opts = append(opts, WithWatchdog(watchdog))
+	}
+	return NewLolcowReconciler(mgr.GetClient(), mgr.GetScheme(), opts...).SetupWithManager(mgr)
+}
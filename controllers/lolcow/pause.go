@@ -0,0 +1,73 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+// Reasons for the Paused condition
+const (
+	reasonSuspended        = "Suspended"
+	reasonPausedAnnotation = "PausedAnnotation"
+)
+
+// paused says if we should leave the lolcow alone, and why
+// spec.suspend wins over the annotation, which must be "true" to count.
+func paused(instance *api.Lolcow) (string, bool) {
+	if instance.Spec.Suspend != nil && *instance.Spec.Suspend {
+		return reasonSuspended, true
+	}
+	if value, ok := instance.Annotations[api.PausedAnnotation]; ok {
+		if pause, err := strconv.ParseBool(value); err == nil && pause {
+			return reasonPausedAnnotation, true
+		}
+	}
+	return "", false
+}
+
+// setPaused reports a paused lolcow in its status, with an event when it pauses
+// The other conditions are left as they were last seen.
+func (r *LolcowReconciler) setPaused(instance *api.Lolcow, reason string) {
+	message := "Reconciling is suspended by spec.suspend"
+	if reason == reasonPausedAnnotation {
+		message = "Reconciling is paused by the " + api.PausedAnnotation + " annotation"
+	}
+	if !meta.IsStatusConditionTrue(instance.Status.Conditions, api.ConditionPaused) {
+		r.event(instance, EventPaused, message)
+	}
+	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               api.ConditionPaused,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: instance.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// clearPaused removes the Paused condition from a lolcow we reconcile again
+func (r *LolcowReconciler) clearPaused(instance *api.Lolcow) {
+	if meta.FindStatusCondition(instance.Status.Conditions, api.ConditionPaused) != nil {
+		r.event(instance, EventResumed, "Reconciling is resumed")
+		meta.RemoveStatusCondition(&instance.Status.Conditions, api.ConditionPaused)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+var _ = Describe("Pause", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	DescribeTable("paused",
+		func(suspend *bool, annotation string, reason string) {
+			lolcow := newFakeLolcow(api.LolcowSpec{Suspend: suspend})
+			if annotation != "" {
+				lolcow.Annotations = map[string]string{api.PausedAnnotation: annotation}
+			}
+			why, ok := paused(lolcow)
+			Expect(why).To(Equal(reason))
+			Expect(ok).To(Equal(reason != ""))
+		},
+		Entry("neither", nil, "", ""),
+		Entry("suspended", boolPtr(true), "", reasonSuspended),
+		Entry("not suspended", boolPtr(false), "", ""),
+		Entry("the annotation", nil, "true", reasonPausedAnnotation),
+		Entry("the annotation, as a bool", nil, "1", reasonPausedAnnotation),
+		Entry("the annotation set to false", nil, "false", ""),
+		Entry("the annotation set to something else", nil, "please", ""),
+		Entry("both, suspend wins", boolPtr(true), "true", reasonSuspended),
+		Entry("the annotation, while not suspended", boolPtr(false), "true", reasonPausedAnnotation),
+	)
+
+	It("leaves a paused lolcow alone until it's resumed", func() {
+		lolcow := newFakeLolcow(api.LolcowSpec{Suspend: boolPtr(true)})
+		r, c, recorder := newFakeReconciler([]client.Object{lolcow})
+		result, err := reconcileFake(r, lolcow)
+		Expect(err).NotTo(HaveOccurred())
+
+		condition := meta.FindStatusCondition(result.Status.Conditions, api.ConditionPaused)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Reason).To(Equal(reasonSuspended))
+		Expect(fakeEvents(recorder)).To(Equal([]string{"Normal Paused Reconciling is suspended by spec.suspend"}))
+		err = c.Get(ctx, client.ObjectKeyFromObject(lolcow), &appsv1.Deployment{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		// Only the first reconcile says so
+		_, err = reconcileFake(r, result)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeEvents(recorder)).To(BeEmpty())
+
+		// Resume it
+		result.Spec.Suspend = nil
+		Expect(c.Update(ctx, result)).To(Succeed())
+		result, err = reconcileFake(r, result)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(meta.FindStatusCondition(result.Status.Conditions, api.ConditionPaused)).To(BeNil())
+		Expect(fakeEvents(recorder)).To(ContainElement("Normal Resumed Reconciling is resumed"))
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(lolcow), &appsv1.Deployment{})).To(Succeed())
+	})
+
+	It("doesn't put back the edits to a paused lolcow's children", func() {
+		lolcow := newFakeLolcow(api.LolcowSpec{})
+		r, c, _ := newFakeReconciler([]client.Object{lolcow})
+		result, err := reconcileFake(r, lolcow)
+		Expect(err).NotTo(HaveOccurred())
+
+		result.Annotations = map[string]string{api.PausedAnnotation: "true"}
+		Expect(c.Update(ctx, result)).To(Succeed())
+		result, err = reconcileFake(r, result)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(meta.FindStatusCondition(result.Status.Conditions, api.ConditionPaused).Reason).To(Equal(reasonPausedAnnotation))
+
+		deployment := &appsv1.Deployment{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(lolcow), deployment)).To(Succeed())
+		replicas := int32(0)
+		deployment.Spec.Replicas = &replicas
+		Expect(c.Update(ctx, deployment)).To(Succeed())
+		_, err = reconcileFake(r, result)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(lolcow), deployment)).To(Succeed())
+		Expect(*deployment.Spec.Replicas).To(BeZero())
+	})
+})
+
+func boolPtr(value bool) *bool {
+	return &value
+}
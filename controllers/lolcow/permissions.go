@@ -0,0 +1,63 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"vsoch/lolcow-operator/api/config/v1alpha1"
+	operatorconfig "vsoch/lolcow-operator/pkg/config"
+	"vsoch/lolcow-operator/pkg/rbac"
+)
+
+// Every verb of the RBAC markers in lolcow_controller.go
+var (
+	allVerbs  = []string{"get", "list", "watch", "create", "update", "patch", "delete"}
+	readVerbs = []string{"get", "list", "watch"}
+)
+
+// Permissions are what the lolcow controller needs in each namespace it
+// watches (the same as its RBAC markers), for the startup check
+func Permissions(config *v1alpha1.OperatorConfig) []rbac.Permission {
+	namespaces := config.Lolcow.WatchNamespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+	permissions := []rbac.Permission{}
+	for _, namespace := range namespaces {
+		needs := []rbac.Permission{
+			{Group: "my.domain", Resource: "lolcows", Verbs: allVerbs},
+			{Group: "my.domain", Resource: "lolcows", Subresource: "status", Verbs: []string{"get", "update", "patch"}},
+			{Group: "my.domain", Resource: "lolcows", Subresource: "finalizers", Verbs: []string{"update"}},
+			{Group: "apps", Resource: "deployments", Verbs: allVerbs},
+			{Resource: "pods", Verbs: readVerbs},
+			{Resource: "services", Verbs: allVerbs},
+			{Resource: "events", Verbs: []string{"create", "patch"}},
+			{Resource: "serviceaccounts", Verbs: allVerbs},
+		}
+		if operatorconfig.Enabled(config.FeatureGates, operatorconfig.NetworkPolicy) {
+			needs = append(needs, rbac.Permission{
+				Group: "networking.k8s.io", Resource: "networkpolicies", Verbs: allVerbs,
+				FeatureGate: operatorconfig.NetworkPolicy,
+			})
+		}
+		for _, need := range needs {
+			need.Namespace = namespace
+			need.NeededBy = ControllerName + " controller"
+			permissions = append(permissions, need)
+		}
+	}
+	return permissions
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+// The design here is inspired by the airflow operator, where each owned
+// resource is a component with its own reconcile step:
+// https://github.com/GoogleCloudPlatform/airflow-operator/blob/master/pkg/controller/controller.go
+
+import (
+	"context"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+	"vsoch/lolcow-operator/pkg/config"
+	"vsoch/lolcow-operator/pkg/metrics"
+)
+
+// Reasons for the conditions the components report
+const (
+	reasonReady          = "Ready"
+	reasonProgressing    = "Progressing"
+	reasonNotRequested   = "NotRequested"
+	reasonReconcileError = "ReconcileError"
+)
+
+// lolcowState is shared by the components of a single reconcile
+type lolcowState struct {
+	instance *api.Lolcow
+
+	// observed holds the objects found or made so far, by component name
+	observed map[string]LolcowResources
+}
+
+// observe records the object a component found or made
+func (s *lolcowState) observe(name string, object LolcowResources) {
+	if s.observed == nil {
+		s.observed = map[string]LolcowResources{}
+	}
+	s.observed[name] = object
+}
+
+// podSelector is the selector the service and network policy should use.
+// Deployment selectors are immutable, so we follow the deployment's.
+func (s *lolcowState) podSelector() map[string]string {
+	if d, ok := s.observed["deployment"].(*appsv1.Deployment); ok && d.Spec.Selector != nil {
+		return d.Spec.Selector.MatchLabels
+	}
+	return selectorLabels(s.instance)
+}
+
+// phaseResult is what a component observed about its resource
+type phaseResult struct {
+	ready   bool
+	reason  string
+	message string
+}
+
+// components are run in order, every reconcile
+// To add a new child type, declare its component in its own file and add it here.
+func (r *LolcowReconciler) components() []component {
+	components := []component{
+		r.serviceAccountComponent(),
+		r.deploymentComponent(),
+		r.serviceComponent(),
+	}
+	if config.Enabled(r.FeatureGates, config.NetworkPolicy) {
+		components = append(components, r.networkPolicyComponent())
+	}
+	return components
+}
+
+// reconcileComponents converges every component in one pass and records their
+// conditions. A failing component doesn't stop the ones after it, and all
+// errors are returned. The observed generation only moves when none failed,
+// and a failing component's condition keeps the generation it last applied.
+func (r *LolcowReconciler) reconcileComponents(ctx context.Context, instance *api.Lolcow) error {
+	state := &lolcowState{instance: instance}
+	errs := []error{}
+	ready := true
+
+	for _, c := range r.components() {
+		start := time.Now()
+		result, err := r.reconcileComponent(ctx, state, c)
+		metrics.ObservePhase(c.name, start)
+		condition := metav1.Condition{
+			Type:               c.condition,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: instance.Generation,
+			Reason:             result.reason,
+			Message:            result.message,
+		}
+		if err != nil {
+			errs = append(errs, err)
+			condition.ObservedGeneration = appliedGeneration(instance, c.condition)
+			condition.Reason = reasonReconcileError
+			condition.Message = err.Error()
+		} else if result.ready {
+			condition.Status = metav1.ConditionTrue
+		}
+		if condition.Reason == "" {
+			condition.Reason = reasonProgressing
+		}
+		ready = ready && condition.Status == metav1.ConditionTrue
+		meta.SetStatusCondition(&instance.Status.Conditions, condition)
+	}
+
+	// Ready summarizes the rest
+	summary := metav1.Condition{
+		Type:               api.ConditionReady,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: instance.Generation,
+		Reason:             reasonProgressing,
+		Message:            "Waiting for the lolcow resources to be ready",
+	}
+	if ready {
+		summary.Status = metav1.ConditionTrue
+		summary.Reason = reasonReady
+		summary.Message = "The lolcow is ready to greet you"
+	}
+	meta.SetStatusCondition(&instance.Status.Conditions, summary)
+	instance.Status.DeployedService = meta.IsStatusConditionTrue(instance.Status.Conditions, api.ConditionServiceReady)
+
+	// The generation is only observed once every component has caught up with it
+	if len(errs) == 0 {
+		instance.Status.ObservedGeneration = instance.Generation
+	}
+	return utilerrors.NewAggregate(errs)
+}
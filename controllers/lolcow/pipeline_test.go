@@ -0,0 +1,138 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+var _ = Describe("Pipeline", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	// created lists the kinds the events say were created, in order
+	created := func(events []string) []string {
+		kinds := []string{}
+		for _, event := range events {
+			if fields := strings.Fields(event); len(fields) > 3 && fields[1] == EventCreated {
+				kinds = append(kinds, fields[3])
+			}
+		}
+		return kinds
+	}
+
+	It("runs the components in order", func() {
+		lolcow := newFakeLolcow(api.LolcowSpec{NetworkPolicy: &api.LolcowNetworkPolicy{}})
+		r, _, recorder := newFakeReconciler([]client.Object{lolcow})
+		Expect(r.components()).To(HaveLen(4))
+		_, err := reconcileFake(r, lolcow)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(created(fakeEvents(recorder))).To(Equal([]string{"ServiceAccount", "Deployment", "Service", "NetworkPolicy"}))
+	})
+
+	It("observes the generation once every component is reconciled", func() {
+		lolcow := newFakeLolcow(api.LolcowSpec{})
+		r, _, _ := newFakeReconciler([]client.Object{lolcow})
+		result, err := reconcileFake(r, lolcow)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Status.ObservedGeneration).To(Equal(int64(1)))
+		for _, condition := range []string{api.ConditionServiceAccountReady, api.ConditionNetworkPolicyReady} {
+			Expect(meta.IsStatusConditionTrue(result.Status.Conditions, condition)).To(BeTrue(), condition)
+		}
+		Expect(meta.FindStatusCondition(result.Status.Conditions, api.ConditionNetworkPolicyReady).Reason).To(Equal(reasonNotRequested))
+
+		// The fake client never rolls out the deployment or gives the service an IP,
+		// but waiting on them isn't an error
+		for _, condition := range []string{api.ConditionDeploymentReady, api.ConditionServiceReady} {
+			waiting := meta.FindStatusCondition(result.Status.Conditions, condition)
+			Expect(waiting.Status).To(Equal(metav1.ConditionFalse), condition)
+			Expect(waiting.Reason).To(Equal(reasonProgressing), condition)
+			Expect(waiting.ObservedGeneration).To(Equal(int64(1)), condition)
+		}
+		Expect(meta.IsStatusConditionTrue(result.Status.Conditions, api.ConditionReady)).To(BeFalse())
+	})
+
+	Context("when a component fails", func() {
+		var (
+			lolcow *api.Lolcow
+			result *api.Lolcow
+			err    error
+			c      client.Client
+		)
+
+		BeforeEach(func() {
+			lolcow = newFakeLolcow(api.LolcowSpec{NetworkPolicy: &api.LolcowNetworkPolicy{}})
+
+			// A service with the same name, managed by someone else
+			isController := true
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "hello-world",
+					Namespace: "default",
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: "apps/v1",
+						Kind:       "Deployment",
+						Name:       "someone-else",
+						UID:        "0f6e7c3a-1b2d-4e5f-8a9b-0c1d2e3f4a5b",
+						Controller: &isController,
+					}},
+				},
+				Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+			}
+			var r *LolcowReconciler
+			r, c, _ = newFakeReconciler([]client.Object{lolcow, service})
+			result, err = reconcileFake(r, lolcow)
+		})
+
+		It("returns its error and reports it in its condition", func() {
+			Expect(err).To(HaveOccurred())
+			condition := meta.FindStatusCondition(result.Status.Conditions, api.ConditionServiceReady)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(reasonReconcileError))
+			Expect(condition.Message).To(Equal(err.Error()))
+			Expect(meta.IsStatusConditionTrue(result.Status.Conditions, api.ConditionReady)).To(BeFalse())
+			Expect(result.Status.DeployedService).To(BeFalse())
+		})
+
+		It("still runs the components after it", func() {
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(lolcow), &appsv1.Deployment{})).To(Succeed())
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(lolcow), &networkingv1.NetworkPolicy{})).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(result.Status.Conditions, api.ConditionNetworkPolicyReady)).To(BeTrue())
+		})
+
+		It("doesn't observe the generation, but the components that applied it do", func() {
+			Expect(result.Status.ObservedGeneration).To(BeZero())
+			Expect(meta.FindStatusCondition(result.Status.Conditions, api.ConditionServiceReady).ObservedGeneration).To(BeZero())
+			Expect(meta.FindStatusCondition(result.Status.Conditions, api.ConditionDeploymentReady).ObservedGeneration).To(Equal(int64(1)))
+		})
+	})
+})
@@ -0,0 +1,117 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+var _ = Describe("Ports", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	children := func(c client.Client, lolcow *api.Lolcow) (*corev1.Container, *corev1.ServicePort) {
+		deployment := &appsv1.Deployment{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(lolcow), deployment)).To(Succeed())
+		service := &corev1.Service{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(lolcow), service)).To(Succeed())
+		Expect(service.Spec.Ports).To(HaveLen(1))
+		return &deployment.Spec.Template.Spec.Containers[0], &service.Spec.Ports[0]
+	}
+
+	It("defaults the container and service ports", func() {
+		lolcow := newFakeLolcow(api.LolcowSpec{})
+		r, c, _ := newFakeReconciler([]client.Object{lolcow})
+		_, err := reconcileFake(r, lolcow)
+		Expect(err).NotTo(HaveOccurred())
+
+		container, port := children(c, lolcow)
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "PORT", Value: "8080"}))
+		Expect(container.Ports).To(Equal([]corev1.ContainerPort{{Name: "lolcow", ContainerPort: 8080, Protocol: corev1.ProtocolTCP}}))
+		Expect(port.Port).To(Equal(api.DefaultServicePort))
+		Expect(port.TargetPort).To(Equal(intstr.FromString("lolcow")))
+		Expect(port.NodePort).To(BeZero())
+	})
+
+	It("passes the ports of the lolcow through", func() {
+		lolcow := newFakeLolcow(api.LolcowSpec{
+			Port: 30080, ServiceType: corev1.ServiceTypeNodePort, ContainerPort: 9000, ServicePort: 8000,
+		})
+		r, c, _ := newFakeReconciler([]client.Object{lolcow})
+		_, err := reconcileFake(r, lolcow)
+		Expect(err).NotTo(HaveOccurred())
+
+		container, port := children(c, lolcow)
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "PORT", Value: "9000"}))
+		Expect(container.Ports[0].ContainerPort).To(Equal(int32(9000)))
+		Expect(port.Port).To(Equal(int32(8000)))
+		Expect(port.NodePort).To(Equal(int32(30080)))
+	})
+
+	It("updates the ports, and tells the user", func() {
+		lolcow := newFakeLolcow(api.LolcowSpec{Port: 30080, ServiceType: corev1.ServiceTypeNodePort})
+		r, c, recorder := newFakeReconciler([]client.Object{lolcow})
+		_, err := reconcileFake(r, lolcow)
+		Expect(err).NotTo(HaveOccurred())
+		fakeEvents(recorder)
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(lolcow), lolcow)).To(Succeed())
+		lolcow.Spec.Port = 30090
+		lolcow.Spec.ContainerPort = 9000
+		lolcow.Generation = 2
+		Expect(c.Update(ctx, lolcow)).To(Succeed())
+		_, err = reconcileFake(r, lolcow)
+		Expect(err).NotTo(HaveOccurred())
+
+		container, port := children(c, lolcow)
+		Expect(container.Ports[0].ContainerPort).To(Equal(int32(9000)))
+		Expect(port.NodePort).To(Equal(int32(30090)))
+		Expect(fakeEvents(recorder)).To(ConsistOf(
+			HavePrefix("Normal PortChanged Updated Deployment hello-world:"),
+			HavePrefix("Normal PortChanged Updated Service hello-world:"),
+		))
+	})
+
+	It("keeps the node port Kubernetes picked, without a port", func() {
+		lolcow := newFakeLolcow(api.LolcowSpec{ServiceType: corev1.ServiceTypeNodePort})
+		r, c, _ := newFakeReconciler([]client.Object{lolcow})
+		_, err := reconcileFake(r, lolcow)
+		Expect(err).NotTo(HaveOccurred())
+
+		service := &corev1.Service{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(lolcow), service)).To(Succeed())
+		service.Spec.Ports[0].NodePort = 31234
+		Expect(c.Update(ctx, service)).To(Succeed())
+
+		_, err = reconcileFake(r, lolcow)
+		Expect(err).NotTo(HaveOccurred())
+		_, port := children(c, lolcow)
+		Expect(port.NodePort).To(Equal(int32(31234)))
+	})
+})
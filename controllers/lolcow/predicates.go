@@ -0,0 +1,75 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// revisionAnnotation is bumped by the deployment controller on each rollout
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// lolcowChanged skips Lolcow updates we don't need to see, like our own
+// status updates. A spec change bumps the generation, and the labels and
+// annotations are copied to the children (or pause the lolcow).
+func lolcowChanged() predicate.Predicate {
+	return predicate.Or(
+		predicate.GenerationChangedPredicate{},
+		predicate.LabelChangedPredicate{},
+		predicate.AnnotationChangedPredicate{},
+	)
+}
+
+// deploymentChanged only passes Deployment updates that matter to us: the
+// spec and metadata we set (drift), and the status deploymentReady reads.
+// A new resourceVersion, managedFields, or the rollout revision don't.
+// It's only for the Deployment watch (see deploymentComponent).
+var deploymentChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		old, oldOK := e.ObjectOld.(*appsv1.Deployment)
+		updated, updatedOK := e.ObjectNew.(*appsv1.Deployment)
+		if !oldOK || !updatedOK {
+			return true
+		}
+		if old.Generation != updated.Generation {
+			return true
+		}
+		if !equality.Semantic.DeepEqual(old.Labels, updated.Labels) ||
+			!equality.Semantic.DeepEqual(withoutRevision(old.Annotations), withoutRevision(updated.Annotations)) ||
+			!equality.Semantic.DeepEqual(old.OwnerReferences, updated.OwnerReferences) ||
+			!old.DeletionTimestamp.Equal(updated.DeletionTimestamp) {
+			return true
+		}
+		return old.Status.ObservedGeneration != updated.Status.ObservedGeneration ||
+			old.Status.UpdatedReplicas != updated.Status.UpdatedReplicas ||
+			old.Status.AvailableReplicas != updated.Status.AvailableReplicas
+	},
+}
+
+// withoutRevision copies annotations, leaving out the rollout revision
+func withoutRevision(annotations map[string]string) map[string]string {
+	copied := map[string]string{}
+	for key, value := range annotations {
+		if key != revisionAnnotation {
+			copied[key] = value
+		}
+	}
+	return copied
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+var _ = Describe("Predicates", func() {
+	DescribeTable("lolcowChanged",
+		func(change func(*api.Lolcow), passes bool) {
+			old := newFakeLolcow(api.LolcowSpec{Greeting: "moo"})
+			old.Labels = map[string]string{"team": "cows"}
+			updated := old.DeepCopy()
+			change(updated)
+			Expect(lolcowChanged().Update(event.UpdateEvent{ObjectOld: old, ObjectNew: updated})).To(Equal(passes))
+		},
+		Entry("our status update", func(l *api.Lolcow) {
+			l.ResourceVersion = "2"
+			l.Status.ObservedGeneration = 1
+		}, false),
+		Entry("a spec change", func(l *api.Lolcow) {
+			l.Spec.Greeting = "baa"
+			l.Generation = 2
+		}, true),
+		Entry("a new label", func(l *api.Lolcow) {
+			l.Labels["team"] = "bulls"
+		}, true),
+		Entry("the paused annotation", func(l *api.Lolcow) {
+			l.Annotations = map[string]string{api.PausedAnnotation: "true"}
+		}, true),
+	)
+
+	DescribeTable("deploymentChanged",
+		func(change func(*appsv1.Deployment), passes bool) {
+			old := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "hello-world",
+					Generation:  1,
+					Labels:      map[string]string{labelName: "lolcow"},
+					Annotations: map[string]string{revisionAnnotation: "1"},
+				},
+				Status: appsv1.DeploymentStatus{ObservedGeneration: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+			}
+			updated := old.DeepCopy()
+			change(updated)
+			Expect(deploymentChanged.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: updated})).To(Equal(passes))
+		},
+		Entry("a new resourceVersion", func(d *appsv1.Deployment) {
+			d.ResourceVersion = "2"
+			d.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kube-controller-manager"}}
+		}, false),
+		Entry("a new rollout revision", func(d *appsv1.Deployment) {
+			d.Annotations[revisionAnnotation] = "2"
+		}, false),
+		Entry("a status we don't read", func(d *appsv1.Deployment) {
+			d.Status.Conditions = []appsv1.DeploymentCondition{{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue}}
+		}, false),
+		Entry("a spec edit", func(d *appsv1.Deployment) {
+			d.Generation = 2
+		}, true),
+		Entry("a label edit", func(d *appsv1.Deployment) {
+			delete(d.Labels, labelName)
+		}, true),
+		Entry("another annotation", func(d *appsv1.Deployment) {
+			d.Annotations["team"] = "cows"
+		}, true),
+		Entry("a new owner", func(d *appsv1.Deployment) {
+			d.OwnerReferences = []metav1.OwnerReference{{Name: "someone-else"}}
+		}, true),
+		Entry("being deleted", func(d *appsv1.Deployment) {
+			now := metav1.Now()
+			d.DeletionTimestamp = &now
+		}, true),
+		Entry("a rollout observed", func(d *appsv1.Deployment) {
+			d.Status.ObservedGeneration = 2
+		}, true),
+		Entry("pods updated", func(d *appsv1.Deployment) {
+			d.Status.UpdatedReplicas = 0
+		}, true),
+		Entry("pods unavailable", func(d *appsv1.Deployment) {
+			d.Status.AvailableReplicas = 0
+		}, true),
+	)
+
+	It("lets deployments be created and deleted", func() {
+		deployment := &appsv1.Deployment{}
+		Expect(deploymentChanged.Create(event.CreateEvent{Object: deployment})).To(BeTrue())
+		Expect(deploymentChanged.Delete(event.DeleteEvent{Object: deployment})).To(BeTrue())
+	})
+
+	It("only filters the Deployment watch", func() {
+		r, _, _ := newFakeReconciler([]client.Object{}, WithFeatureGates(map[string]bool{}))
+		for _, c := range r.components() {
+			if c.name == "deployment" {
+				Expect(c.predicates).To(HaveLen(1), c.name)
+			} else {
+				Expect(c.predicates).To(BeEmpty(), c.name)
+			}
+		}
+	})
+})
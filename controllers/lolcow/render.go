@@ -0,0 +1,98 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	logctrl "sigs.k8s.io/controller-runtime/pkg/log"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+// Rendered is a child of a Lolcow, as a reconcile would leave it
+type Rendered struct {
+	Object client.Object
+
+	// Action is created, updated, deleted, or empty if nothing would change
+	Action string
+
+	// Changes lists the fields an update would change, as "path: old -> new"
+	Changes []string
+}
+
+// Render runs the components of a lolcow the way Reconcile does, but
+// nothing is written. The children are read from reader, which can be the
+// cluster or a saved state, and any that aren't there are created.
+func (r *LolcowReconciler) Render(ctx context.Context, instance *api.Lolcow, reader client.Reader) ([]Rendered, error) {
+	renderer := *r
+	renderer.Client = readOnly{Reader: reader, scheme: r.Scheme}
+	renderer.Recorder = nil
+	ctx = logctrl.IntoContext(ctx, logr.Discard())
+
+	// A lolcow from a file has no uid, so take the one we own the children with
+	instance = instance.DeepCopy()
+	if instance.UID == "" {
+		existing := &api.Lolcow{}
+		if err := reader.Get(ctx, client.ObjectKeyFromObject(instance), existing); err == nil {
+			instance.UID = existing.UID
+		}
+	}
+
+	state := &lolcowState{instance: instance}
+	rendered := []Rendered{}
+	for _, c := range renderer.components() {
+		result, err := renderer.reconcileComponent(ctx, state, c)
+		if err != nil {
+			return nil, err
+		}
+		if result.object == nil {
+			continue
+		}
+		gvk, err := apiutil.GVKForObject(result.object, r.Scheme)
+		if err != nil {
+			return nil, err
+		}
+		result.object.GetObjectKind().SetGroupVersionKind(gvk)
+		rendered = append(rendered, Rendered{Object: result.object, Action: result.action, Changes: result.changes})
+	}
+	return rendered, nil
+}
+
+// readOnly reads from a client, and pretends the writes worked
+type readOnly struct {
+	client.Reader
+	scheme *runtime.Scheme
+}
+
+func (readOnly) Create(context.Context, client.Object, ...client.CreateOption) error { return nil }
+func (readOnly) Delete(context.Context, client.Object, ...client.DeleteOption) error { return nil }
+func (readOnly) Update(context.Context, client.Object, ...client.UpdateOption) error { return nil }
+func (readOnly) Patch(context.Context, client.Object, client.Patch, ...client.PatchOption) error {
+	return nil
+}
+func (readOnly) DeleteAllOf(context.Context, client.Object, ...client.DeleteAllOfOption) error {
+	return nil
+}
+func (c readOnly) Status() client.StatusWriter { return c }
+func (c readOnly) Scheme() *runtime.Scheme     { return c.scheme }
+func (c readOnly) RESTMapper() meta.RESTMapper { return nil }
@@ -17,38 +17,116 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
+
 	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+	"vsoch/lolcow-operator/pkg/config"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
-	ctrl "sigs.k8s.io/controller-runtime"
 )
 
+// servicePort is the port the lolcow service exposes
+func servicePort(v *api.Lolcow) int32 {
+	if v.Spec.ServicePort == 0 {
+		return api.DefaultServicePort
+	}
+	return v.Spec.ServicePort
+}
+
+// serviceType is how the lolcow is exposed: its own type, the operator's, or LoadBalancer
+func (r *LolcowReconciler) serviceType(v *api.Lolcow) corev1.ServiceType {
+	if v.Spec.ServiceType != "" {
+		return v.Spec.ServiceType
+	}
+	if r.DefaultServiceType == "" {
+		return config.DefaultServiceType
+	}
+	return r.DefaultServiceType
+}
+
 // createService creates a backend service
 func (r *LolcowReconciler) createService(instance *api.Lolcow) *corev1.Service {
 
-	labels := labels(instance, "backend")
-
 	// We shouldn't need this, as the port comes from the manifest
 	service := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      instance.Name,
-			Namespace: instance.Namespace,
+			Name:        instance.Name,
+			Namespace:   instance.Namespace,
+			Labels:      r.childLabels(instance, "backend"),
+			Annotations: r.childAnnotations(instance),
 		},
 		Spec: corev1.ServiceSpec{
-			Selector: labels,
+			Selector: selectorLabels(instance),
 			Ports: []corev1.ServicePort{
 				{
+					Name:       "lolcow",
 					Protocol:   corev1.ProtocolTCP,
-					Port:       80,
-					TargetPort: intstr.FromInt(8080),
-					NodePort:   instance.Spec.Port,
+					Port:       servicePort(instance),
+					TargetPort: intstr.FromString("lolcow"),
 				},
 			},
-			Type: corev1.ServiceTypeLoadBalancer,
+			Type: r.serviceType(instance),
 		},
 	}
-	ctrl.SetControllerReference(instance, service, r.Scheme)
+
+	// A ClusterIP service doesn't have a node port
+	if service.Spec.Type != corev1.ServiceTypeClusterIP {
+		service.Spec.Ports[0].NodePort = instance.Spec.Port
+	}
+	r.markPropagated(instance, &service.ObjectMeta)
 	return service
 }
+
+// serviceComponent exposes the lolcow, selecting the pods the deployment selects
+func (r *LolcowReconciler) serviceComponent() component {
+	return component{
+		name:      "service",
+		condition: api.ConditionServiceReady,
+		object:    &corev1.Service{},
+		desired: func(state *lolcowState) LolcowResources {
+			service := r.createService(state.instance)
+			service.Spec.Selector = state.podSelector()
+			return service
+		},
+		mutate: func(ctx context.Context, state *lolcowState, existing, desired LolcowResources) error {
+			service := existing.(*corev1.Service)
+			want := desired.(*corev1.Service)
+
+			// Did the port change (and needs to be updated?)
+			// We set the fields one by one to keep the defaults we don't own
+			port := want.Spec.Ports[0]
+			if len(service.Spec.Ports) == 0 {
+				service.Spec.Ports = want.Spec.Ports
+			}
+			service.Spec.Ports[0].Name = port.Name
+			service.Spec.Ports[0].Port = port.Port
+			service.Spec.Ports[0].TargetPort = port.TargetPort
+			// Without a port, keep the node port Kubernetes picked
+			if port.NodePort != 0 || want.Spec.Type == corev1.ServiceTypeClusterIP {
+				service.Spec.Ports[0].NodePort = port.NodePort
+			}
+			service.Spec.Type = want.Spec.Type
+			service.Spec.Selector = want.Spec.Selector
+			mergeMetadata(&service.ObjectMeta, want.ObjectMeta, propagatedKeys(service.ObjectMeta))
+			return nil
+		},
+		ready: func(state *lolcowState, object LolcowResources) phaseResult {
+			return serviceReady(object.(*corev1.Service))
+		},
+	}
+}
+
+// serviceReady is true once the service has a cluster IP. A load balancer
+// might never get an external address (e.g., minikube without a tunnel), so
+// we only mention it.
+func serviceReady(s *corev1.Service) phaseResult {
+	if s.Spec.ClusterIP == "" {
+		return phaseResult{reason: reasonProgressing, message: "Waiting for Service " + s.Name + " to get a cluster IP"}
+	}
+	if s.Spec.Type == corev1.ServiceTypeLoadBalancer && len(s.Status.LoadBalancer.Ingress) == 0 {
+		return phaseResult{ready: true, reason: reasonReady, message: "Service " + s.Name + " is ready, the load balancer is pending"}
+	}
+	return phaseResult{ready: true, reason: reasonReady, message: "Service " + s.Name + " is ready"}
+}
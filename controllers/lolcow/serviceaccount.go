@@ -0,0 +1,82 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// serviceAccountName is the account the lolcow pods run as
+func serviceAccountName(v *api.Lolcow) string {
+	if v.Spec.ServiceAccountName != "" {
+		return v.Spec.ServiceAccountName
+	}
+	return v.Name
+}
+
+// automountToken defaults to false - the lolcow doesn't need the API
+func automountToken(v *api.Lolcow) *bool {
+	automount := false
+	if v.Spec.AutomountServiceAccountToken != nil {
+		automount = *v.Spec.AutomountServiceAccountToken
+	}
+	return &automount
+}
+
+// createServiceAccount creates a dedicated (and permission-less) account
+// We intentionally bind no Role to it: the lolcow only serves a web page
+func (r *LolcowReconciler) createServiceAccount(instance *api.Lolcow) *corev1.ServiceAccount {
+	account := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      serviceAccountName(instance),
+			Namespace: instance.Namespace,
+			Labels:    r.labels(instance, "backend"),
+		},
+		AutomountServiceAccountToken: automountToken(instance),
+		ImagePullSecrets:             instance.Spec.ImagePullSecrets,
+	}
+	return account
+}
+
+// serviceAccountComponent makes sure the dedicated account exists, unless
+// the user brought their own (which is then theirs to manage)
+func (r *LolcowReconciler) serviceAccountComponent() component {
+	return component{
+		name:      "serviceaccount",
+		condition: api.ConditionServiceAccountReady,
+		object:    &corev1.ServiceAccount{},
+		enabled: func(state *lolcowState) bool {
+			return state.instance.Spec.ServiceAccountName == ""
+		},
+		desired: func(state *lolcowState) LolcowResources {
+			return r.createServiceAccount(state.instance)
+		},
+		mutate: func(ctx context.Context, state *lolcowState, existing, desired LolcowResources) error {
+			account := existing.(*corev1.ServiceAccount)
+			want := desired.(*corev1.ServiceAccount)
+			mergeMetadata(&account.ObjectMeta, want.ObjectMeta, propagated{})
+			account.ImagePullSecrets = want.ImagePullSecrets
+			account.AutomountServiceAccountToken = want.AutomountServiceAccountToken
+			return nil
+		},
+	}
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+var _ = Describe("ServiceAccount", func() {
+	var ctx context.Context
+	pullSecrets := []corev1.LocalObjectReference{{Name: "my-registry"}}
+	key := types.NamespacedName{Namespace: "default", Name: "hello-world"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	get := func(c client.Client, name string) (*corev1.ServiceAccount, *appsv1.Deployment) {
+		account := &corev1.ServiceAccount{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, account); err != nil {
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			account = nil
+		}
+		deployment := &appsv1.Deployment{}
+		Expect(c.Get(ctx, key, deployment)).To(Succeed())
+		return account, deployment
+	}
+
+	It("runs the pods as a dedicated account without a token", func() {
+		lolcow := newFakeLolcow(api.LolcowSpec{ImagePullSecrets: pullSecrets})
+		r, c, _ := newFakeReconciler([]client.Object{lolcow})
+		_, err := reconcileFake(r, lolcow)
+		Expect(err).NotTo(HaveOccurred())
+
+		account, deployment := get(c, "hello-world")
+		Expect(account).NotTo(BeNil())
+		Expect(metav1.IsControlledBy(account, lolcow)).To(BeTrue())
+		Expect(*account.AutomountServiceAccountToken).To(BeFalse())
+		Expect(account.ImagePullSecrets).To(Equal(pullSecrets))
+
+		pod := deployment.Spec.Template.Spec
+		Expect(pod.ServiceAccountName).To(Equal("hello-world"))
+		Expect(*pod.AutomountServiceAccountToken).To(BeFalse())
+		Expect(pod.ImagePullSecrets).To(BeEmpty())
+	})
+
+	It("mounts the token when asked to", func() {
+		automount := true
+		lolcow := newFakeLolcow(api.LolcowSpec{AutomountServiceAccountToken: &automount})
+		r, c, _ := newFakeReconciler([]client.Object{lolcow})
+		_, err := reconcileFake(r, lolcow)
+		Expect(err).NotTo(HaveOccurred())
+
+		account, deployment := get(c, "hello-world")
+		Expect(*account.AutomountServiceAccountToken).To(BeTrue())
+		Expect(*deployment.Spec.Template.Spec.AutomountServiceAccountToken).To(BeTrue())
+	})
+
+	It("leaves an account the user brings alone, and puts the pull secrets on the pods", func() {
+		theirs := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "my-cow-account", Namespace: "default"}}
+		lolcow := newFakeLolcow(api.LolcowSpec{ServiceAccountName: "my-cow-account", ImagePullSecrets: pullSecrets})
+		r, c, _ := newFakeReconciler([]client.Object{lolcow, theirs})
+		result, err := reconcileFake(r, lolcow)
+		Expect(err).NotTo(HaveOccurred())
+
+		account, deployment := get(c, "my-cow-account")
+		Expect(account.OwnerReferences).To(BeEmpty())
+		Expect(account.ImagePullSecrets).To(BeEmpty())
+		Expect(deployment.Spec.Template.Spec.ServiceAccountName).To(Equal("my-cow-account"))
+		Expect(deployment.Spec.Template.Spec.ImagePullSecrets).To(Equal(pullSecrets))
+
+		dedicated, _ := get(c, "hello-world")
+		Expect(dedicated).To(BeNil())
+		Expect(result.Status.Conditions).To(ContainElement(And(
+			HaveField("Type", api.ConditionServiceAccountReady),
+			HaveField("Reason", reasonNotRequested),
+		)))
+	})
+
+	It("removes the dedicated account when the user brings their own", func() {
+		lolcow := newFakeLolcow(api.LolcowSpec{})
+		r, c, _ := newFakeReconciler([]client.Object{lolcow})
+		_, err := reconcileFake(r, lolcow)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.Get(ctx, key, lolcow)).To(Succeed())
+		lolcow.Spec.ServiceAccountName = "my-cow-account"
+		Expect(c.Update(ctx, lolcow)).To(Succeed())
+		_, err = reconcileFake(r, lolcow)
+		Expect(err).NotTo(HaveOccurred())
+
+		dedicated, deployment := get(c, "hello-world")
+		Expect(dedicated).To(BeNil())
+		Expect(deployment.Spec.Template.Spec.ServiceAccountName).To(Equal("my-cow-account"))
+	})
+
+	It("doesn't remove an account named after the lolcow that isn't ours", func() {
+		theirs := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "hello-world", Namespace: "default"}}
+		lolcow := newFakeLolcow(api.LolcowSpec{ServiceAccountName: "my-cow-account"})
+		r, c, _ := newFakeReconciler([]client.Object{lolcow, theirs})
+		_, err := reconcileFake(r, lolcow)
+		Expect(err).NotTo(HaveOccurred())
+
+		account, _ := get(c, "hello-world")
+		Expect(account).NotTo(BeNil())
+	})
+})
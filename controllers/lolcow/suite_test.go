@@ -54,10 +54,14 @@ var _ = BeforeSuite(func() {
 
 	By("bootstrapping test environment")
 	testEnv = &envtest.Environment{
-		CRDDirectoryPaths:     []string{filepath.Join("..", "config", "crd", "bases")},
+		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "config", "crd", "bases")},
 		ErrorIfCRDPathMissing: true,
 	}
 
+	// The CRD's CEL validation rules are alpha in the envtest Kubernetes (1.24)
+	testEnv.ControlPlane.GetAPIServer().Configure().
+		Append("feature-gates", "CustomResourceValidationExpressions=true")
+
 	var err error
 	// cfg is defined in this file globally.
 	cfg, err = testEnv.Start()
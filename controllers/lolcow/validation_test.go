@@ -0,0 +1,161 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+// There's no webhook in this suite, so these are the CRD's own rules
+var _ = Describe("Lolcow CRD validation", func() {
+	var (
+		ctx       context.Context
+		namespace string
+		count     int
+	)
+
+	newLolcow := func(spec api.LolcowSpec) *api.Lolcow {
+		return &api.Lolcow{
+			ObjectMeta: metav1.ObjectMeta{Name: "hello-world", Namespace: namespace},
+			Spec:       spec,
+		}
+	}
+
+	// expectInvalid expects the API server to reject an object with a message
+	expectInvalid := func(err error, message string) {
+		Expect(apierrors.IsInvalid(err)).To(BeTrue(), "expected an invalid error, got %v", err)
+		Expect(err.Error()).To(ContainSubstring(message))
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		count++
+		namespace = fmt.Sprintf("validation-%d", count)
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+	})
+
+	It("accepts a lolcow without a port", func() {
+		Expect(k8sClient.Create(ctx, newLolcow(api.LolcowSpec{ServiceType: corev1.ServiceTypeNodePort, Greeting: "Moo"}))).To(Succeed())
+	})
+
+	It("rejects a port out of range", func() {
+		expectInvalid(k8sClient.Create(ctx, newLolcow(api.LolcowSpec{Port: 70000, ServiceType: corev1.ServiceTypeNodePort})), "spec.port")
+	})
+
+	It("rejects a port outside the node port range", func() {
+		expectInvalid(k8sClient.Create(ctx, newLolcow(api.LolcowSpec{Port: 8080, ServiceType: corev1.ServiceTypeNodePort})), "spec.port")
+	})
+
+	It("accepts a port without a serviceType, like lolcows from before serviceType", func() {
+		Expect(k8sClient.Create(ctx, newLolcow(api.LolcowSpec{Port: 30080, Greeting: "Moo"}))).To(Succeed())
+	})
+
+	It("rejects a port for a ClusterIP service", func() {
+		lolcow := newLolcow(api.LolcowSpec{Port: 30080, ServiceType: corev1.ServiceTypeClusterIP})
+		expectInvalid(k8sClient.Create(ctx, lolcow), "port is only allowed for NodePort or LoadBalancer services")
+	})
+
+	It("rejects an unknown service type", func() {
+		expectInvalid(k8sClient.Create(ctx, newLolcow(api.LolcowSpec{ServiceType: "ExternalName"})), "spec.serviceType")
+	})
+
+	It("rejects a long greeting", func() {
+		lolcow := newLolcow(api.LolcowSpec{Greeting: strings.Repeat("Moo", api.MaxGreetingLength)})
+		expectInvalid(k8sClient.Create(ctx, lolcow), "spec.greeting")
+	})
+
+	It("rejects a greeting and greetingFrom together", func() {
+		lolcow := newLolcow(api.LolcowSpec{
+			Greeting: "Moo",
+			GreetingFrom: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "greetings"},
+				Key:                  "monday",
+			},
+		})
+		expectInvalid(k8sClient.Create(ctx, lolcow), "greeting and greetingFrom are mutually exclusive")
+	})
+
+	Context("networkPolicy peers", func() {
+		withPeer := func(peer api.LolcowNetworkPeer) *api.Lolcow {
+			return newLolcow(api.LolcowSpec{NetworkPolicy: &api.LolcowNetworkPolicy{
+				AllowedFrom: []api.LolcowNetworkPeer{peer},
+			}})
+		}
+		selector := &metav1.LabelSelector{MatchLabels: map[string]string{"team": "cows"}}
+
+		It("accepts a cidr, or selectors", func() {
+			Expect(k8sClient.Create(ctx, withPeer(api.LolcowNetworkPeer{CIDR: "10.0.0.0/16", Except: []string{"10.0.1.0/24"}}))).To(Succeed())
+			lolcow := withPeer(api.LolcowNetworkPeer{NamespaceSelector: selector, PodSelector: selector})
+			lolcow.Name = "selectors"
+			Expect(k8sClient.Create(ctx, lolcow)).To(Succeed())
+		})
+
+		It("rejects a cidr with a selector, which would be an invalid NetworkPolicy", func() {
+			lolcow := withPeer(api.LolcowNetworkPeer{CIDR: "10.0.0.0/16", PodSelector: selector})
+			expectInvalid(k8sClient.Create(ctx, lolcow), "cidr may not be set with a namespaceSelector or podSelector")
+		})
+
+		It("rejects an empty peer", func() {
+			expectInvalid(k8sClient.Create(ctx, withPeer(api.LolcowNetworkPeer{})), "set a cidr, or a namespaceSelector and/or podSelector")
+		})
+
+		It("rejects except without a cidr", func() {
+			lolcow := withPeer(api.LolcowNetworkPeer{PodSelector: selector, Except: []string{"10.0.1.0/24"}})
+			expectInvalid(k8sClient.Create(ctx, lolcow), "except may only be set with a cidr")
+		})
+	})
+
+	Context("serviceType", func() {
+		var lolcow *api.Lolcow
+
+		BeforeEach(func() {
+			lolcow = newLolcow(api.LolcowSpec{Greeting: "Moo"})
+			Expect(k8sClient.Create(ctx, lolcow)).To(Succeed())
+		})
+
+		It("can be set once", func() {
+			lolcow.Spec.ServiceType = corev1.ServiceTypeNodePort
+			Expect(k8sClient.Update(ctx, lolcow)).To(Succeed())
+		})
+
+		It("is immutable", func() {
+			lolcow.Spec.ServiceType = corev1.ServiceTypeNodePort
+			Expect(k8sClient.Update(ctx, lolcow)).To(Succeed())
+			lolcow.Spec.ServiceType = corev1.ServiceTypeLoadBalancer
+			expectInvalid(k8sClient.Update(ctx, lolcow), "serviceType is immutable")
+		})
+
+		It("can't be removed", func() {
+			lolcow.Spec.ServiceType = corev1.ServiceTypeNodePort
+			Expect(k8sClient.Update(ctx, lolcow)).To(Succeed())
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(lolcow), lolcow)).To(Succeed())
+			lolcow.Spec.ServiceType = ""
+			expectInvalid(k8sClient.Update(ctx, lolcow), "serviceType can't be removed once set")
+		})
+	})
+})
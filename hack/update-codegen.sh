@@ -0,0 +1,44 @@
+#!/usr/bin/env bash
+
+# Generate the typed client for the Lolcow API into pkg/client: a versioned
+# clientset (with a fake for tests), listers, shared informers and apply
+# configurations. Run it with make generate-client, after changing api/lolcow.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+ROOT=$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)
+BIN=${BIN:-${ROOT}/bin}
+MODULE=vsoch/lolcow-operator
+API=${MODULE}/api/lolcow/v1alpha1
+CLIENT=${MODULE}/pkg/client
+HEADER=${ROOT}/hack/boilerplate.go.txt
+
+# The generators write to <output-base>/<package>, so we don't need a GOPATH
+OUTPUT=$(mktemp -d)
+trap 'rm -rf "${OUTPUT}"' EXIT
+
+cd "${ROOT}"
+"${BIN}/applyconfiguration-gen" --go-header-file "${HEADER}" --output-base "${OUTPUT}" \
+  --input-dirs "${API}" \
+  --output-package "${CLIENT}/applyconfiguration"
+
+"${BIN}/client-gen" --go-header-file "${HEADER}" --output-base "${OUTPUT}" \
+  --clientset-name versioned \
+  --input-base "${MODULE}/api" --input lolcow/v1alpha1 \
+  --apply-configuration-package "${CLIENT}/applyconfiguration" \
+  --output-package "${CLIENT}/clientset"
+
+"${BIN}/lister-gen" --go-header-file "${HEADER}" --output-base "${OUTPUT}" \
+  --input-dirs "${API}" \
+  --output-package "${CLIENT}/listers"
+
+"${BIN}/informer-gen" --go-header-file "${HEADER}" --output-base "${OUTPUT}" \
+  --input-dirs "${API}" \
+  --versioned-clientset-package "${CLIENT}/clientset/versioned" \
+  --listers-package "${CLIENT}/listers" \
+  --output-package "${CLIENT}/informers"
+
+rm -rf "${ROOT}/pkg/client"
+cp -r "${OUTPUT}/${CLIENT}" "${ROOT}/pkg/client"
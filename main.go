@@ -17,7 +17,12 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
@@ -25,16 +30,31 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	authorizationclient "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	configv1alpha1 "vsoch/lolcow-operator/api/config/v1alpha1"
 	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
 	"vsoch/lolcow-operator/controllers/core"
-	controllers "vsoch/lolcow-operator/controllers/lolcow"
-	"vsoch/lolcow-operator/pkg/lolcow"
+	"vsoch/lolcow-operator/pkg/certs"
+	// The controllers register themselves with core
+	_ "vsoch/lolcow-operator/controllers/lolcow"
+	"vsoch/lolcow-operator/pkg/config"
+	"vsoch/lolcow-operator/pkg/health"
+	"vsoch/lolcow-operator/pkg/logging"
+	"vsoch/lolcow-operator/pkg/metrics"
+	"vsoch/lolcow-operator/pkg/rbac"
+	"vsoch/lolcow-operator/pkg/render"
+	"vsoch/lolcow-operator/pkg/tracing"
+	"vsoch/lolcow-operator/pkg/validate"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -43,6 +63,16 @@ var (
 	setupLog = ctrl.Log.WithName("setup")
 )
 
+// subcommands exit 1 when they found something (the found error), like
+// changes or invalid lolcows, and 2 when they fail
+var subcommands = map[string]struct {
+	run   func(ctx context.Context, args []string, in io.Reader, out, errOut io.Writer) error
+	found error
+}{
+	"render":   {render.Run, render.ErrDifferent},
+	"validate": {validate.Run, validate.ErrInvalid},
+}
+
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
@@ -52,77 +82,280 @@ func init() {
 
 func main() {
 
-	// TODO @vsoch refactor this into cleaner functions
-	var metricsAddr string
-	var enableLeaderElection bool
-	var probeAddr string
-	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
-	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
-	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
-		"Enable leader election for controller manager. "+
-			"Enabling this will ensure there is only one active controller manager.")
-	opts := zap.Options{Development: true}
+	// Subcommands don't start the manager, see pkg/render and pkg/validate
+	if len(os.Args) > 1 {
+		if command, ok := subcommands[os.Args[1]]; ok {
+			err := command.run(context.Background(), os.Args[2:], os.Stdin, os.Stdout, os.Stderr)
+			switch {
+			case err == nil, errors.Is(err, flag.ErrHelp):
+				os.Exit(0)
+			case errors.Is(err, command.found):
+				os.Exit(1)
+			default:
+				fmt.Fprintf(os.Stderr, "error: %s\n", err)
+				os.Exit(2)
+			}
+		}
+	}
+
+	// Flags override the configuration file (--config), if there is one
+	configFlags := config.BindFlags(flag.CommandLine)
+	var tracingOpts tracing.Options
+	flag.StringVar(&tracingOpts.Exporter, "tracing-exporter", tracing.ExporterNone,
+		"Where to send reconcile traces: none, stdout, or otlp (OTLP/HTTP to --tracing-endpoint).")
+	flag.StringVar(&tracingOpts.Endpoint, "tracing-endpoint", "",
+		"The OTLP/HTTP collector endpoint. Defaults to OTEL_EXPORTER_OTLP_ENDPOINT, then "+tracing.DefaultEndpoint+".")
+	flag.Float64Var(&tracingOpts.SampleRatio, "tracing-sample-ratio", 1,
+		"The fraction of reconciles to trace, between 0 and 1.")
+	// JSON at info level by default, --zap-devel for the console
+	opts := zap.Options{}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
-
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
-		Port:                   9443,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "9bf5ca16.my.domain",
-		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
-		// when the Manager ends. This requires the binary to immediately end when the
-		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
-		// speeds up voluntary leader transitions as the new leader don't have to wait
-		// LeaseDuration time first.
-		//
-		// In the default scaffold provided, the program ends immediately after
-		// the manager stops, so would be fine to enable this option. However,
-		// if you are doing or is intended to do any operation such as perform cleanups
-		// after the manager stops then its usage might be unsafe.
-		// LeaderElectionReleaseOnCancel: true,
-	})
+	ctrl.SetLogger(logging.New(&opts))
+
+	// Reject a bad configuration now, rather than half way through
+	operatorConfig, err := configFlags.Load()
+	if err == nil {
+		err = config.Validate(operatorConfig)
+	}
+	if err == nil {
+		_, err = core.Selected(operatorConfig.Controllers)
+	}
+	if err != nil {
+		setupLog.Error(err, "invalid configuration", "file", configFlags.File)
+		os.Exit(1)
+	}
+
+	// Tracing is off by default, and flushed when the manager stops
+	stopTracing, err := tracing.Setup(context.Background(), tracingOpts)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	defer func() {
+		if err := stopTracing(context.Background()); err != nil {
+			setupLog.Error(err, "problem flushing traces")
+		}
+	}()
+
+	// The manager options (metrics, probes, leader election...) come from the configuration
+	// See config/manager/controller_manager_config.yaml for what can be set
+	options, err := ctrl.Options{Scheme: scheme}.AndFrom(operatorConfig)
+	if err != nil {
+		setupLog.Error(err, "unable to read manager options")
+		os.Exit(1)
+	}
+
+	// The manager serves plain HTTP metrics. Secure metrics are served (on
+	// the same address) by our own server instead, see pkg/metrics.
+	if operatorConfig.SecureMetrics.Enabled {
+		options.MetricsBindAddress = "0"
+	}
+
+	// Only cache (and so only reconcile) the lolcows in these namespaces
+	// Each namespace only needs a Role there, see config/namespaced
+	namespaces := operatorConfig.Lolcow.WatchNamespaces
+	switch len(namespaces) {
+	case 0:
+		setupLog.Info("watching all namespaces")
+	case 1:
+		setupLog.Info("watching a single namespace", "namespace", namespaces[0])
+		options.Namespace = namespaces[0]
+	default:
+		setupLog.Info("watching namespaces", "namespaces", namespaces)
+		options.NewCache = cache.MultiNamespacedCacheBuilder(namespaces)
+	}
+
+	// Find out about a wrong role now, rather than when a Create fails
+	restConfig := ctrl.GetConfigOrDie()
+	if err := checkPermissions(context.Background(), restConfig, operatorConfig); err != nil {
+		setupLog.Error(err, "missing permissions", "permissionCheck", operatorConfig.PermissionCheck)
+		os.Exit(1)
+	}
+
+	mgr, err := ctrl.NewManager(restConfig, options)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
-	if failedCtrl, err := core.SetupControllers(mgr); err != nil {
+	// Every registered controller (see controllers/core) that --controllers selects
+	if failedCtrl, err := core.SetupControllers(mgr, operatorConfig); err != nil {
 		setupLog.Error(err, "Unable to create controller", "controller", failedCtrl)
 		os.Exit(1)
 	}
 
-	// Create new greeter
-	greeter := lolcow.NewGreeter("Hello from the Lolcow!")
+	// The same defaulting and validation runs offline in lolcow-operator validate
+	ctx := ctrl.SetupSignalHandler()
+	if config.Enabled(operatorConfig.FeatureGates, config.Webhooks) {
+		if err := (&api.Lolcow{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Lolcow")
+			os.Exit(1)
+		}
 
-	// Primary lolcow controller
-	if err = (&controllers.LolcowReconciler{
-		Client:  mgr.GetClient(),
-		Scheme:  mgr.GetScheme(),
-		Greeter: greeter,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Lolcow")
-		os.Exit(1)
+		// Without cert-manager, we make the certificate, before the webhook server needs it
+		if operatorConfig.WebhookCertificates.SelfManaged {
+			if err := setupCertRotator(ctx, mgr, operatorConfig); err != nil {
+				setupLog.Error(err, "unable to set up the webhook certificates")
+				os.Exit(1)
+			}
+		}
+	}
+
+	// Lolcow metrics are served with the controller-runtime ones
+	metrics.Register(mgr.GetClient(), operatorConfig.Lolcow.DefaultGreeting)
+	if operatorConfig.SecureMetrics.Enabled {
+		server, err := metrics.NewSecureServer(mgr.GetConfig(), operatorConfig.Metrics.BindAddress, operatorConfig.SecureMetrics.CertDir)
+		if err == nil {
+			err = mgr.Add(server)
+		}
+		if err != nil {
+			setupLog.Error(err, "unable to set up the secure metrics server")
+			os.Exit(1)
+		}
 	}
 
 	//+kubebuilder:scaffold:builder
 
+	// The lolcow controller adds its own liveness check, see pkg/health
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+	if err := setupReadyzChecks(mgr, config.Enabled(operatorConfig.FeatureGates, config.Webhooks)); err != nil {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
+		stopTracing(context.Background())
 		os.Exit(1)
 	}
 }
+
+// setupReadyzChecks makes the manager ready once its caches synced, the
+// lolcow API is served and, with webhooks, the webhook server answers TLS
+func setupReadyzChecks(mgr ctrl.Manager, webhooks bool) error {
+	probeConfig := rest.CopyConfig(mgr.GetConfig())
+	probeConfig.Timeout = health.ProbeTimeout
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(probeConfig)
+	if err != nil {
+		return err
+	}
+	if err := mgr.AddReadyzCheck("caches", health.CacheSynced(mgr.GetCache())); err != nil {
+		return err
+	}
+	if err := mgr.AddReadyzCheck("lolcows", health.ResourceServed(discoveryClient, api.GroupVersion.WithResource("lolcows"))); err != nil {
+		return err
+	}
+	if webhooks {
+		return mgr.AddReadyzCheck("webhook", mgr.GetWebhookServer().StartedChecker())
+	}
+	return nil
+}
+
+// checkPermissions asks the API server if the manager may do everything it
+// needs, and prints a table of what's missing. To degrade, the feature gates
+// that miss a permission are turned off, and the rest is only logged.
+func checkPermissions(ctx context.Context, restConfig *rest.Config, operatorConfig *configv1alpha1.OperatorConfig) error {
+	mode := operatorConfig.PermissionCheck
+	if mode == config.PermissionCheckOff {
+		return nil
+	}
+	permissions, err := core.Permissions(operatorConfig)
+	if err != nil {
+		return err
+	}
+
+	// Leader election and the webhook certificates are in the manager's
+	// namespace, which we can't tell outside of a cluster
+	namespace, namespaceErr := certs.Namespace()
+	if election := operatorConfig.LeaderElection; election != nil && election.LeaderElect != nil && *election.LeaderElect {
+		electionNamespace := election.ResourceNamespace
+		if electionNamespace == "" {
+			electionNamespace = namespace
+		}
+		if electionNamespace != "" {
+			permissions = append(permissions, rbac.LeaderElection(electionNamespace, election.ResourceLock)...)
+		}
+	}
+	if config.Enabled(operatorConfig.FeatureGates, config.Webhooks) && operatorConfig.WebhookCertificates.SelfManaged && namespaceErr == nil {
+		permissions = append(permissions, webhookCertOptions(operatorConfig, namespace).Permissions()...)
+	}
+	if operatorConfig.SecureMetrics.Enabled {
+		permissions = append(permissions, metrics.SecurePermissions...)
+	}
+
+	client, err := authorizationclient.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	missing, err := rbac.Check(ctx, client.SelfSubjectAccessReviews(), permissions)
+	if err != nil {
+		if mode == config.PermissionCheckFail {
+			return err
+		}
+		setupLog.Error(err, "unable to check permissions, carrying on")
+		return nil
+	}
+	if len(missing) == 0 {
+		setupLog.Info("🔐️ The manager has every permission it needs", "checked", len(permissions))
+		return nil
+	}
+
+	fmt.Fprintln(os.Stderr, "🔐️ The manager is missing these permissions, see config/rbac:")
+	if err := rbac.PrintTable(os.Stderr, missing); err != nil {
+		return err
+	}
+	if mode == config.PermissionCheckFail {
+		return fmt.Errorf("%d permission(s) missing", len(missing))
+	}
+	for _, gate := range rbac.FeatureGates(missing) {
+		setupLog.Info("⚠️ Turning off a feature gate, it misses permissions", "featureGate", gate)
+		if operatorConfig.FeatureGates == nil {
+			operatorConfig.FeatureGates = map[string]bool{}
+		}
+		operatorConfig.FeatureGates[gate] = false
+	}
+	if required := rbac.Required(missing); len(required) > 0 {
+		setupLog.Info("⚠️ Carrying on without permissions, some lolcows will fail to reconcile", "missing", len(required))
+	}
+	return nil
+}
+
+// webhookCertOptions are the rotator options, from the configuration
+func webhookCertOptions(operatorConfig *configv1alpha1.OperatorConfig, namespace string) certs.Options {
+	webhookCerts := operatorConfig.WebhookCertificates
+	return certs.Options{
+		Secret:                          types.NamespacedName{Namespace: namespace, Name: webhookCerts.SecretName},
+		Service:                         types.NamespacedName{Namespace: namespace, Name: webhookCerts.ServiceName},
+		CertDir:                         operatorConfig.Webhook.CertDir,
+		MutatingWebhookConfigurations:   webhookCerts.MutatingWebhookConfigurations,
+		ValidatingWebhookConfigurations: webhookCerts.ValidatingWebhookConfigurations,
+		CustomResourceDefinitions:       webhookCerts.CustomResourceDefinitions,
+		Validity:                        webhookCerts.Validity.Duration,
+	}
+}
+
+// setupCertRotator makes (or renews) the webhook certificates now, and
+// keeps them fresh while the manager runs
+func setupCertRotator(ctx context.Context, mgr ctrl.Manager, operatorConfig *configv1alpha1.OperatorConfig) error {
+	namespace, err := certs.Namespace()
+	if err != nil {
+		return err
+	}
+	rotator, err := certs.NewRotator(mgr.GetConfig(), webhookCertOptions(operatorConfig, namespace))
+	if err != nil {
+		return err
+	}
+	if err := rotator.Sync(ctx); err != nil {
+		return err
+	}
+	if err := mgr.AddReadyzCheck("webhook-certs", func(*http.Request) error { return rotator.Ready() }); err != nil {
+		return err
+	}
+	return mgr.Add(rotator)
+}
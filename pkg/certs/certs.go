@@ -0,0 +1,175 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package certs makes and rotates the webhook serving certificates, for
+// clusters without cert-manager. The manager keeps a CA and a serving
+// certificate in a Secret, writes them where the webhook server reads them,
+// and puts the CA in the webhook configurations and CRDs.
+package certs
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// KeyPair is a certificate and its key, parsed and as PEM
+type KeyPair struct {
+	Cert    *x509.Certificate
+	Key     *ecdsa.PrivateKey
+	CertPEM []byte
+	KeyPEM  []byte
+}
+
+// NewCA makes a self-signed CA, valid from now for validity
+func NewCA(now time.Time, validity time.Duration) (*KeyPair, error) {
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "lolcow-operator-webhook-ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	return newKeyPair(template, nil)
+}
+
+// NewServingCert makes a serving certificate for dnsNames, signed by the CA
+// It doesn't outlive the CA.
+func NewServingCert(ca *KeyPair, dnsNames []string, now time.Time, validity time.Duration) (*KeyPair, error) {
+	notAfter := now.Add(validity)
+	if notAfter.After(ca.Cert.NotAfter) {
+		notAfter = ca.Cert.NotAfter
+	}
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:    dnsNames,
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	return newKeyPair(template, ca)
+}
+
+// newKeyPair signs a template with the CA, or itself without one
+func newKeyPair(template *x509.Certificate, ca *KeyPair) (*KeyPair, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template.SerialNumber, err = rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	parent, signer := template, key
+	if ca != nil {
+		parent, signer = ca.Cert, ca.Key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyPair{
+		Cert:    cert,
+		Key:     key,
+		CertPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		KeyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
+
+// ParseKeyPair reads a PEM certificate and key. Only the first certificate
+// counts, the rest (e.g., a previous CA in a bundle) are ignored.
+func ParseKeyPair(certPEM, keyPEM []byte) (*KeyPair, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil || certBlock.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no PEM certificate")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, fmt.Errorf("no PEM key")
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if !key.PublicKey.Equal(cert.PublicKey) {
+		return nil, fmt.Errorf("the key doesn't match the certificate")
+	}
+	return &KeyPair{
+		Cert:    cert,
+		Key:     key,
+		CertPEM: pem.EncodeToMemory(certBlock),
+		KeyPEM:  keyPEM,
+	}, nil
+}
+
+// Bundle is the CA, and the previous one while it's still valid, so
+// certificates signed by either are trusted during a CA rotation
+func Bundle(ca *KeyPair, previous []byte, now time.Time) []byte {
+	bundle := bytes.NewBuffer(append([]byte{}, ca.CertPEM...))
+	for rest := previous; len(rest) > 0; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil || now.After(cert.NotAfter) || cert.Equal(ca.Cert) {
+			continue
+		}
+		bundle.Write(pem.EncodeToMemory(block))
+	}
+	return bundle.Bytes()
+}
+
+// expiring is true once less than a third of a certificate's life is left
+func expiring(cert *x509.Certificate, now time.Time) bool {
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	return now.After(cert.NotAfter.Add(-lifetime / 3))
+}
+
+// servesFor is true if the certificate is signed by the CA, and valid for every name
+func servesFor(cert *x509.Certificate, ca *x509.Certificate, dnsNames []string) bool {
+	if err := cert.CheckSignatureFrom(ca); err != nil {
+		return false
+	}
+	for _, name := range dnsNames {
+		if cert.VerifyHostname(name) != nil {
+			return false
+		}
+	}
+	return true
+}
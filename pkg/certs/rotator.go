@@ -0,0 +1,423 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logctrl "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"vsoch/lolcow-operator/pkg/rbac"
+)
+
+// Keys of the Secret. The serving certificate is in the usual tls.crt and
+// tls.key, like cert-manager's, and ca.crt may have the previous CA too.
+const (
+	CACertKey = "ca.crt"
+	CAKeyKey  = "ca.key"
+)
+
+// Defaults for the Rotator
+const (
+	DefaultValidity      = 365 * 24 * time.Hour
+	DefaultCheckInterval = 10 * time.Minute
+
+	// caValidityFactor is how much longer than the serving certificate the CA lasts
+	caValidityFactor = 10
+)
+
+// DefaultCertDir is where the webhook server reads its certificate, unless
+// the configuration (webhook.certDir) says otherwise
+var DefaultCertDir = filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs")
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
+}
+
+// Options for a Rotator
+type Options struct {
+
+	// Secret keeps the CA and the serving certificate
+	Secret types.NamespacedName
+
+	// Service serves the webhooks, the certificate is for its DNS names
+	Service types.NamespacedName
+
+	// CertDir is where the webhook server reads tls.crt and tls.key
+	CertDir string
+
+	// MutatingWebhookConfigurations, ValidatingWebhookConfigurations and
+	// CustomResourceDefinitions (with a conversion webhook) get the CA
+	MutatingWebhookConfigurations   []string
+	ValidatingWebhookConfigurations []string
+	CustomResourceDefinitions       []string
+
+	// Validity of the serving certificate, which is renewed when a third
+	// of it is left. The CA lasts ten times as long.
+	Validity time.Duration
+
+	// CheckInterval is how often the certificates are checked
+	CheckInterval time.Duration
+}
+
+// Rotator keeps the webhook certificates valid. Every replica of the
+// manager runs one: they share the Secret, and whoever gets there first
+// makes (or renews) the certificates.
+type Rotator struct {
+	Options
+
+	client client.Client
+	now    func() time.Time
+
+	mutex sync.Mutex
+	err   error
+}
+
+// NewRotator makes a Rotator, with its own client: it runs before the
+// manager's cache starts, so the webhook server has a certificate
+func NewRotator(config *rest.Config, options Options) (*Rotator, error) {
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+	return newRotator(c, options), nil
+}
+
+func newRotator(c client.Client, options Options) *Rotator {
+	if options.CertDir == "" {
+		options.CertDir = DefaultCertDir
+	}
+	if options.Validity == 0 {
+		options.Validity = DefaultValidity
+	}
+	if options.CheckInterval == 0 {
+		options.CheckInterval = DefaultCheckInterval
+	}
+	return &Rotator{
+		Options: options,
+		client:  c,
+		now:     time.Now,
+		err:     fmt.Errorf("the webhook certificates are not ready yet"),
+	}
+}
+
+// DNSNames are the names the serving certificate is for
+func (r *Rotator) DNSNames() []string {
+	service := r.Service.Name + "." + r.Service.Namespace + ".svc"
+	return []string{service, service + ".cluster.local"}
+}
+
+// Start checks the certificates every CheckInterval until ctx is done
+func (r *Rotator) Start(ctx context.Context) error {
+	log := logctrl.FromContext(ctx).WithName("certs")
+	ctx = logctrl.IntoContext(ctx, log)
+	ticker := time.NewTicker(r.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.Sync(ctx); err != nil {
+				log.Error(err, "🔐️ cannot refresh the webhook certificates, will retry")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is false, every replica serves webhooks
+func (r *Rotator) NeedLeaderElection() bool {
+	return false
+}
+
+// Ready is nil once the webhook server has a valid certificate, and the
+// CA is in everything that calls it
+func (r *Rotator) Ready() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.err
+}
+
+// Sync makes or renews the certificates, writes them to CertDir, and
+// injects the CA. Call it once before the manager starts.
+func (r *Rotator) Sync(ctx context.Context) error {
+	err := r.sync(ctx)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.err = err
+	return err
+}
+
+func (r *Rotator) sync(ctx context.Context) error {
+	secret, err := r.secret(ctx)
+	if err != nil {
+		return err
+	}
+	if err := r.writeFiles(ctx, secret); err != nil {
+		return err
+	}
+	return r.inject(ctx, secret.Data[CACertKey])
+}
+
+// secret gets the Secret with valid certificates, making or renewing them
+// if needed. A conflict means another replica got there first, so we read
+// what it did.
+func (r *Rotator) secret(ctx context.Context) (*corev1.Secret, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		secret, err := r.refreshSecret(ctx)
+		if err == nil {
+			return secret, nil
+		}
+		if !apierrors.IsAlreadyExists(err) && !apierrors.IsConflict(err) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("the webhook certificates secret %s keeps changing", r.Secret)
+}
+
+// refreshSecret makes, renews or just reads the Secret, once
+func (r *Rotator) refreshSecret(ctx context.Context) (*corev1.Secret, error) {
+	log := logctrl.FromContext(ctx)
+	secret := &corev1.Secret{}
+	err := r.client.Get(ctx, r.Secret, secret)
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: r.Secret.Name, Namespace: r.Secret.Namespace},
+			Type:       corev1.SecretTypeTLS,
+		}
+		if err := r.renew(secret); err != nil {
+			return nil, err
+		}
+		if err := r.client.Create(ctx, secret); err != nil {
+			return nil, err
+		}
+		log.Info("🔐️ Created the webhook certificates", "secret", r.Secret)
+		return secret, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	renew, reason := r.needsRenewal(secret)
+	if !renew {
+		return secret, nil
+	}
+	log.Info("🔐️ Renewing the webhook certificates", "secret", r.Secret, "reason", reason.Error())
+	if err := r.renew(secret); err != nil {
+		return nil, err
+	}
+	if err := r.client.Update(ctx, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// needsRenewal says if the certificates in a secret need renewing, and why
+func (r *Rotator) needsRenewal(secret *corev1.Secret) (bool, error) {
+	now := r.now()
+	ca, err := ParseKeyPair(secret.Data[CACertKey], secret.Data[CAKeyKey])
+	if err != nil {
+		return true, fmt.Errorf("bad CA: %w", err)
+	}
+	cert, err := ParseKeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return true, fmt.Errorf("bad serving certificate: %w", err)
+	}
+	switch {
+	case now.Add(r.Validity).After(ca.Cert.NotAfter):
+		return true, fmt.Errorf("the CA expires before a new certificate would")
+	case expiring(cert.Cert, now):
+		return true, fmt.Errorf("the serving certificate expires at %s", cert.Cert.NotAfter.Format(time.RFC3339))
+	case !servesFor(cert.Cert, ca.Cert, r.DNSNames()):
+		return true, fmt.Errorf("the serving certificate isn't for %s", strings.Join(r.DNSNames(), ", "))
+	}
+	return false, nil
+}
+
+// renew puts a new serving certificate in the secret, and a new CA if the
+// old one is missing or expiring (the old one stays in the bundle)
+func (r *Rotator) renew(secret *corev1.Secret) error {
+	now := r.now()
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	ca, err := ParseKeyPair(secret.Data[CACertKey], secret.Data[CAKeyKey])
+	if err != nil || now.Add(r.Validity).After(ca.Cert.NotAfter) {
+		previous := secret.Data[CACertKey]
+		ca, err = NewCA(now, caValidityFactor*r.Validity)
+		if err != nil {
+			return err
+		}
+		secret.Data[CACertKey] = Bundle(ca, previous, now)
+		secret.Data[CAKeyKey] = ca.KeyPEM
+	}
+	cert, err := NewServingCert(ca, r.DNSNames(), now, r.Validity)
+	if err != nil {
+		return err
+	}
+	secret.Data[corev1.TLSCertKey] = cert.CertPEM
+	secret.Data[corev1.TLSPrivateKeyKey] = cert.KeyPEM
+	return nil
+}
+
+// writeFiles puts the serving certificate where the webhook server reads it
+// The server watches the files, and picks up a new certificate by itself.
+func (r *Rotator) writeFiles(ctx context.Context, secret *corev1.Secret) error {
+	if err := os.MkdirAll(r.CertDir, 0o700); err != nil {
+		return err
+	}
+	for _, key := range []string{corev1.TLSPrivateKeyKey, corev1.TLSCertKey} {
+		path := filepath.Join(r.CertDir, key)
+		current, err := os.ReadFile(path)
+		if err == nil && bytes.Equal(current, secret.Data[key]) {
+			continue
+		}
+		if err := os.WriteFile(path, secret.Data[key], 0o600); err != nil {
+			return err
+		}
+		logctrl.FromContext(ctx).Info("🔐️ Wrote the webhook certificate", "file", path)
+	}
+	return nil
+}
+
+// inject puts the CA bundle in the webhook configurations and the CRD
+// conversion webhooks. Missing ones are skipped: they may not be installed.
+func (r *Rotator) inject(ctx context.Context, bundle []byte) error {
+	log := logctrl.FromContext(ctx)
+	for _, name := range r.MutatingWebhookConfigurations {
+		configuration := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		err := r.update(ctx, name, configuration, func() bool {
+			changed := false
+			for i := range configuration.Webhooks {
+				changed = setBundle(&configuration.Webhooks[i].ClientConfig.CABundle, bundle) || changed
+			}
+			return changed
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, name := range r.ValidatingWebhookConfigurations {
+		configuration := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		err := r.update(ctx, name, configuration, func() bool {
+			changed := false
+			for i := range configuration.Webhooks {
+				changed = setBundle(&configuration.Webhooks[i].ClientConfig.CABundle, bundle) || changed
+			}
+			return changed
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, name := range r.CustomResourceDefinitions {
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		err := r.update(ctx, name, crd, func() bool {
+			conversion := crd.Spec.Conversion
+			if conversion == nil || conversion.Webhook == nil || conversion.Webhook.ClientConfig == nil {
+				return false
+			}
+			return setBundle(&conversion.Webhook.ClientConfig.CABundle, bundle)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	log.V(1).Info("🔐️ The CA is injected")
+	return nil
+}
+
+// update gets a cluster scoped object, and updates it if mutate changed it
+func (r *Rotator) update(ctx context.Context, name string, object client.Object, mutate func() bool) error {
+	log := logctrl.FromContext(ctx)
+	err := r.client.Get(ctx, types.NamespacedName{Name: name}, object)
+	if apierrors.IsNotFound(err) {
+		log.V(1).Info("🔐️ Not injecting the CA, it isn't there", "name", name)
+		return nil
+	}
+	if err != nil || !mutate() {
+		return err
+	}
+	if err := r.client.Update(ctx, object); err != nil {
+		return fmt.Errorf("injecting the CA into %s: %w", name, err)
+	}
+	log.Info("🔐️ Injected the CA", "name", name)
+	return nil
+}
+
+// setBundle sets a caBundle, and says if it changed
+func setBundle(caBundle *[]byte, bundle []byte) bool {
+	if bytes.Equal(*caBundle, bundle) {
+		return false
+	}
+	*caBundle = bundle
+	return true
+}
+
+// Permissions are what the rotator needs, the same as config/webhookcerts
+func (o Options) Permissions() []rbac.Permission {
+	neededBy := "webhook certificates"
+	permissions := []rbac.Permission{
+		{Resource: "secrets", Verbs: []string{"create"}, Namespace: o.Secret.Namespace, NeededBy: neededBy},
+		{Resource: "secrets", Verbs: []string{"get", "update"}, Namespace: o.Secret.Namespace, Name: o.Secret.Name, NeededBy: neededBy},
+	}
+	named := func(group, resource string, names []string) {
+		for _, name := range names {
+			permissions = append(permissions, rbac.Permission{
+				Group: group, Resource: resource, Verbs: []string{"get", "update"}, Name: name, NeededBy: neededBy,
+			})
+		}
+	}
+	named("admissionregistration.k8s.io", "mutatingwebhookconfigurations", o.MutatingWebhookConfigurations)
+	named("admissionregistration.k8s.io", "validatingwebhookconfigurations", o.ValidatingWebhookConfigurations)
+	named("apiextensions.k8s.io", "customresourcedefinitions", o.CustomResourceDefinitions)
+	return permissions
+}
+
+// Namespace is the namespace the manager runs in: POD_NAMESPACE (from the
+// downward API), or its service account's
+func Namespace() (string, error) {
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace, nil
+	}
+	namespace, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+	if err != nil {
+		return "", fmt.Errorf("cannot tell the manager's namespace, set POD_NAMESPACE: %w", err)
+	}
+	return strings.TrimSpace(string(namespace)), nil
+}
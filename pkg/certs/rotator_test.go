@@ -0,0 +1,240 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Rotator", func() {
+	var (
+		ctx     context.Context
+		c       client.Client
+		rotator *Rotator
+		dir     string
+		now     time.Time
+	)
+
+	secretKey := types.NamespacedName{Namespace: "lolcow-operator-system", Name: "webhook-certs"}
+
+	// secret is the Secret as the rotator left it
+	secret := func() *corev1.Secret {
+		secret := &corev1.Secret{}
+		Expect(c.Get(ctx, secretKey, secret)).To(Succeed())
+		return secret
+	}
+
+	// servingCert is the certificate the webhook server would load
+	servingCert := func() *x509.Certificate {
+		pair, err := tls.LoadX509KeyPair(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"))
+		Expect(err).NotTo(HaveOccurred())
+		cert, err := x509.ParseCertificate(pair.Certificate[0])
+		Expect(err).NotTo(HaveOccurred())
+		return cert
+	}
+
+	// verify checks the serving certificate is trusted by a CA bundle
+	verify := func(cert *x509.Certificate, bundle []byte) error {
+		roots := x509.NewCertPool()
+		Expect(roots.AppendCertsFromPEM(bundle)).To(BeTrue())
+		_, err := cert.Verify(x509.VerifyOptions{
+			DNSName:     "webhook-service.lolcow-operator-system.svc",
+			Roots:       roots,
+			CurrentTime: now,
+		})
+		return err
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		var err error
+		dir, err = os.MkdirTemp("", "certs")
+		Expect(err).NotTo(HaveOccurred())
+		now = time.Now()
+
+		sideEffects := admissionregistrationv1.SideEffectClassNone
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Name: "mutating"},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name:                    "mlolcow.kb.io",
+					SideEffects:             &sideEffects,
+					AdmissionReviewVersions: []string{"v1"},
+				}},
+			},
+			&admissionregistrationv1.ValidatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Name: "validating"},
+				Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+					Name:                    "vlolcow.kb.io",
+					SideEffects:             &sideEffects,
+					AdmissionReviewVersions: []string{"v1"},
+				}},
+			},
+			&apiextensionsv1.CustomResourceDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: "lolcows.my.domain"},
+				Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+					Conversion: &apiextensionsv1.CustomResourceConversion{
+						Strategy: apiextensionsv1.WebhookConverter,
+						Webhook: &apiextensionsv1.WebhookConversion{
+							ClientConfig:             &apiextensionsv1.WebhookClientConfig{},
+							ConversionReviewVersions: []string{"v1"},
+						},
+					},
+				},
+			},
+			&apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "others.my.domain"}},
+		).Build()
+
+		rotator = newRotator(c, Options{
+			Secret:                          secretKey,
+			Service:                         types.NamespacedName{Namespace: "lolcow-operator-system", Name: "webhook-service"},
+			CertDir:                         dir,
+			MutatingWebhookConfigurations:   []string{"mutating", "not-installed"},
+			ValidatingWebhookConfigurations: []string{"validating"},
+			CustomResourceDefinitions:       []string{"lolcows.my.domain", "others.my.domain"},
+			Validity:                        90 * 24 * time.Hour,
+		})
+		rotator.now = func() time.Time { return now }
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("isn't ready before it syncs", func() {
+		Expect(rotator.Ready()).To(HaveOccurred())
+	})
+
+	It("makes the certificates, writes them and injects the CA", func() {
+		Expect(rotator.Sync(ctx)).To(Succeed())
+		Expect(rotator.Ready()).To(Succeed())
+
+		bundle := secret().Data[CACertKey]
+		Expect(verify(servingCert(), bundle)).To(Succeed())
+		Expect(servingCert().DNSNames).To(ConsistOf(
+			"webhook-service.lolcow-operator-system.svc",
+			"webhook-service.lolcow-operator-system.svc.cluster.local",
+		))
+
+		mutating := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		Expect(c.Get(ctx, types.NamespacedName{Name: "mutating"}, mutating)).To(Succeed())
+		Expect(mutating.Webhooks[0].ClientConfig.CABundle).To(Equal(bundle))
+		validating := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		Expect(c.Get(ctx, types.NamespacedName{Name: "validating"}, validating)).To(Succeed())
+		Expect(validating.Webhooks[0].ClientConfig.CABundle).To(Equal(bundle))
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		Expect(c.Get(ctx, types.NamespacedName{Name: "lolcows.my.domain"}, crd)).To(Succeed())
+		Expect(crd.Spec.Conversion.Webhook.ClientConfig.CABundle).To(Equal(bundle))
+		Expect(c.Get(ctx, types.NamespacedName{Name: "others.my.domain"}, crd)).To(Succeed())
+		Expect(crd.Spec.Conversion).To(BeNil())
+	})
+
+	It("leaves fresh certificates alone", func() {
+		Expect(rotator.Sync(ctx)).To(Succeed())
+		before := secret()
+		now = now.Add(30 * 24 * time.Hour)
+		Expect(rotator.Sync(ctx)).To(Succeed())
+		Expect(secret().ResourceVersion).To(Equal(before.ResourceVersion))
+	})
+
+	It("renews the serving certificate when a third is left, with the same CA", func() {
+		Expect(rotator.Sync(ctx)).To(Succeed())
+		before := secret()
+		oldCert := servingCert()
+
+		now = now.Add(61 * 24 * time.Hour)
+		Expect(rotator.Sync(ctx)).To(Succeed())
+		after := secret()
+		Expect(after.Data[CACertKey]).To(Equal(before.Data[CACertKey]))
+		Expect(servingCert().SerialNumber).NotTo(Equal(oldCert.SerialNumber))
+		Expect(verify(servingCert(), after.Data[CACertKey])).To(Succeed())
+	})
+
+	It("renews the CA before it expires, trusting the old one meanwhile", func() {
+		Expect(rotator.Sync(ctx)).To(Succeed())
+		oldCA := secret().Data[CACertKey]
+
+		// The CA lasts ten validities, and is renewed once a new
+		// serving certificate would outlive it
+		now = now.Add(9*90*24*time.Hour + time.Hour)
+		Expect(rotator.Sync(ctx)).To(Succeed())
+		bundle := secret().Data[CACertKey]
+		Expect(bundle).NotTo(Equal(oldCA))
+		Expect(bundle).To(ContainSubstring(string(oldCA)))
+		Expect(verify(servingCert(), bundle)).To(Succeed())
+	})
+
+	It("renews a certificate for another service", func() {
+		Expect(rotator.Sync(ctx)).To(Succeed())
+		rotator.Service.Name = "other-service"
+		Expect(rotator.Sync(ctx)).To(Succeed())
+		Expect(servingCert().DNSNames).To(ContainElement("other-service.lolcow-operator-system.svc"))
+	})
+
+	It("replaces a broken secret", func() {
+		Expect(c.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: secretKey.Name, Namespace: secretKey.Namespace},
+			Data:       map[string][]byte{corev1.TLSCertKey: []byte("moo")},
+		})).To(Succeed())
+		Expect(rotator.Sync(ctx)).To(Succeed())
+		Expect(verify(servingCert(), secret().Data[CACertKey])).To(Succeed())
+	})
+
+	It("uses what another replica made", func() {
+		other := newRotator(c, rotator.Options)
+		other.now = rotator.now
+		Expect(other.Sync(ctx)).To(Succeed())
+		made := secret()
+
+		Expect(rotator.Sync(ctx)).To(Succeed())
+		Expect(secret().ResourceVersion).To(Equal(made.ResourceVersion))
+		Expect(verify(servingCert(), made.Data[CACertKey])).To(Succeed())
+	})
+
+	It("needs the secret, and the configurations and CRDs it injects", func() {
+		needs := map[string][]string{}
+		for _, permission := range rotator.Permissions() {
+			Expect(permission.NeededBy).To(Equal("webhook certificates"))
+			key := permission.Resource + "/" + permission.Name
+			needs[key] = append(needs[key], permission.Verbs...)
+		}
+		Expect(needs).To(Equal(map[string][]string{
+			"secrets/":                                    {"create"},
+			"secrets/" + secretKey.Name:                   {"get", "update"},
+			"mutatingwebhookconfigurations/mutating":      {"get", "update"},
+			"mutatingwebhookconfigurations/not-installed": {"get", "update"},
+			"validatingwebhookconfigurations/validating":  {"get", "update"},
+			"customresourcedefinitions/lolcows.my.domain": {"get", "update"},
+			"customresourcedefinitions/others.my.domain":  {"get", "update"},
+		}))
+	})
+})
@@ -0,0 +1,143 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+// LolcowSpecApplyConfiguration represents an declarative configuration of the LolcowSpec type for use
+// with apply.
+type LolcowSpecApplyConfiguration struct {
+	Port                         *int32                                 `json:"port,omitempty"`
+	ServiceType                  *v1.ServiceType                        `json:"serviceType,omitempty"`
+	ContainerPort                *int32                                 `json:"containerPort,omitempty"`
+	ServicePort                  *int32                                 `json:"servicePort,omitempty"`
+	Greeting                     *string                                `json:"greeting,omitempty"`
+	GreetingFrom                 *v1.ConfigMapKeySelector               `json:"greetingFrom,omitempty"`
+	Image                        *string                                `json:"image,omitempty"`
+	NetworkPolicy                *LolcowNetworkPolicyApplyConfiguration `json:"networkPolicy,omitempty"`
+	ServiceAccountName           *string                                `json:"serviceAccountName,omitempty"`
+	AutomountServiceAccountToken *bool                                  `json:"automountServiceAccountToken,omitempty"`
+	ImagePullSecrets             []v1.LocalObjectReference              `json:"imagePullSecrets,omitempty"`
+	Suspend                      *bool                                  `json:"suspend,omitempty"`
+}
+
+// LolcowSpecApplyConfiguration constructs an declarative configuration of the LolcowSpec type for use with
+// apply.
+func LolcowSpec() *LolcowSpecApplyConfiguration {
+	return &LolcowSpecApplyConfiguration{}
+}
+
+// WithPort sets the Port field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Port field is set to the value of the last call.
+func (b *LolcowSpecApplyConfiguration) WithPort(value int32) *LolcowSpecApplyConfiguration {
+	b.Port = &value
+	return b
+}
+
+// WithServiceType sets the ServiceType field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ServiceType field is set to the value of the last call.
+func (b *LolcowSpecApplyConfiguration) WithServiceType(value v1.ServiceType) *LolcowSpecApplyConfiguration {
+	b.ServiceType = &value
+	return b
+}
+
+// WithContainerPort sets the ContainerPort field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ContainerPort field is set to the value of the last call.
+func (b *LolcowSpecApplyConfiguration) WithContainerPort(value int32) *LolcowSpecApplyConfiguration {
+	b.ContainerPort = &value
+	return b
+}
+
+// WithServicePort sets the ServicePort field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ServicePort field is set to the value of the last call.
+func (b *LolcowSpecApplyConfiguration) WithServicePort(value int32) *LolcowSpecApplyConfiguration {
+	b.ServicePort = &value
+	return b
+}
+
+// WithGreeting sets the Greeting field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Greeting field is set to the value of the last call.
+func (b *LolcowSpecApplyConfiguration) WithGreeting(value string) *LolcowSpecApplyConfiguration {
+	b.Greeting = &value
+	return b
+}
+
+// WithGreetingFrom sets the GreetingFrom field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the GreetingFrom field is set to the value of the last call.
+func (b *LolcowSpecApplyConfiguration) WithGreetingFrom(value v1.ConfigMapKeySelector) *LolcowSpecApplyConfiguration {
+	b.GreetingFrom = &value
+	return b
+}
+
+// WithImage sets the Image field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Image field is set to the value of the last call.
+func (b *LolcowSpecApplyConfiguration) WithImage(value string) *LolcowSpecApplyConfiguration {
+	b.Image = &value
+	return b
+}
+
+// WithNetworkPolicy sets the NetworkPolicy field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the NetworkPolicy field is set to the value of the last call.
+func (b *LolcowSpecApplyConfiguration) WithNetworkPolicy(value *LolcowNetworkPolicyApplyConfiguration) *LolcowSpecApplyConfiguration {
+	b.NetworkPolicy = value
+	return b
+}
+
+// WithServiceAccountName sets the ServiceAccountName field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ServiceAccountName field is set to the value of the last call.
+func (b *LolcowSpecApplyConfiguration) WithServiceAccountName(value string) *LolcowSpecApplyConfiguration {
+	b.ServiceAccountName = &value
+	return b
+}
+
+// WithAutomountServiceAccountToken sets the AutomountServiceAccountToken field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the AutomountServiceAccountToken field is set to the value of the last call.
+func (b *LolcowSpecApplyConfiguration) WithAutomountServiceAccountToken(value bool) *LolcowSpecApplyConfiguration {
+	b.AutomountServiceAccountToken = &value
+	return b
+}
+
+// WithImagePullSecrets adds the given value to the ImagePullSecrets field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the ImagePullSecrets field.
+func (b *LolcowSpecApplyConfiguration) WithImagePullSecrets(values ...v1.LocalObjectReference) *LolcowSpecApplyConfiguration {
+	for i := range values {
+		b.ImagePullSecrets = append(b.ImagePullSecrets, values[i])
+	}
+	return b
+}
+
+// WithSuspend sets the Suspend field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Suspend field is set to the value of the last call.
+func (b *LolcowSpecApplyConfiguration) WithSuspend(value bool) *LolcowSpecApplyConfiguration {
+	b.Suspend = &value
+	return b
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package applyconfiguration
+
+import (
+	v1alpha1 "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+	lolcowv1alpha1 "vsoch/lolcow-operator/pkg/client/applyconfiguration/lolcow/v1alpha1"
+
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ForKind returns an apply configuration type for the given GroupVersionKind, or nil if no
+// apply configuration type exists for the given GroupVersionKind.
+func ForKind(kind schema.GroupVersionKind) interface{} {
+	switch kind {
+	// Group=my.domain, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithKind("Lolcow"):
+		return &lolcowv1alpha1.LolcowApplyConfiguration{}
+	case v1alpha1.SchemeGroupVersion.WithKind("LolcowNetworkPeer"):
+		return &lolcowv1alpha1.LolcowNetworkPeerApplyConfiguration{}
+	case v1alpha1.SchemeGroupVersion.WithKind("LolcowNetworkPolicy"):
+		return &lolcowv1alpha1.LolcowNetworkPolicyApplyConfiguration{}
+	case v1alpha1.SchemeGroupVersion.WithKind("LolcowSpec"):
+		return &lolcowv1alpha1.LolcowSpecApplyConfiguration{}
+	case v1alpha1.SchemeGroupVersion.WithKind("LolcowStatus"):
+		return &lolcowv1alpha1.LolcowStatusApplyConfiguration{}
+
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+	json "encoding/json"
+	"fmt"
+	v1alpha1 "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+	lolcowv1alpha1 "vsoch/lolcow-operator/pkg/client/applyconfiguration/lolcow/v1alpha1"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeLolcows implements LolcowInterface
+type FakeLolcows struct {
+	Fake *FakeLolcowV1alpha1
+	ns   string
+}
+
+var lolcowsResource = schema.GroupVersionResource{Group: "my.domain", Version: "v1alpha1", Resource: "lolcows"}
+
+var lolcowsKind = schema.GroupVersionKind{Group: "my.domain", Version: "v1alpha1", Kind: "Lolcow"}
+
+// Get takes name of the lolcow, and returns the corresponding lolcow object, and an error if there is any.
+func (c *FakeLolcows) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.Lolcow, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(lolcowsResource, c.ns, name), &v1alpha1.Lolcow{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.Lolcow), err
+}
+
+// List takes label and field selectors, and returns the list of Lolcows that match those selectors.
+func (c *FakeLolcows) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.LolcowList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(lolcowsResource, lolcowsKind, c.ns, opts), &v1alpha1.LolcowList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.LolcowList{ListMeta: obj.(*v1alpha1.LolcowList).ListMeta}
+	for _, item := range obj.(*v1alpha1.LolcowList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested lolcows.
+func (c *FakeLolcows) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(lolcowsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a lolcow and creates it.  Returns the server's representation of the lolcow, and an error, if there is any.
+func (c *FakeLolcows) Create(ctx context.Context, lolcow *v1alpha1.Lolcow, opts v1.CreateOptions) (result *v1alpha1.Lolcow, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(lolcowsResource, c.ns, lolcow), &v1alpha1.Lolcow{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.Lolcow), err
+}
+
+// Update takes the representation of a lolcow and updates it. Returns the server's representation of the lolcow, and an error, if there is any.
+func (c *FakeLolcows) Update(ctx context.Context, lolcow *v1alpha1.Lolcow, opts v1.UpdateOptions) (result *v1alpha1.Lolcow, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(lolcowsResource, c.ns, lolcow), &v1alpha1.Lolcow{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.Lolcow), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeLolcows) UpdateStatus(ctx context.Context, lolcow *v1alpha1.Lolcow, opts v1.UpdateOptions) (*v1alpha1.Lolcow, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(lolcowsResource, "status", c.ns, lolcow), &v1alpha1.Lolcow{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.Lolcow), err
+}
+
+// Delete takes name of the lolcow and deletes it. Returns an error if one occurs.
+func (c *FakeLolcows) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteActionWithOptions(lolcowsResource, c.ns, name, opts), &v1alpha1.Lolcow{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeLolcows) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(lolcowsResource, c.ns, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.LolcowList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched lolcow.
+func (c *FakeLolcows) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.Lolcow, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(lolcowsResource, c.ns, name, pt, data, subresources...), &v1alpha1.Lolcow{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.Lolcow), err
+}
+
+// Apply takes the given apply declarative configuration, applies it and returns the applied lolcow.
+func (c *FakeLolcows) Apply(ctx context.Context, lolcow *lolcowv1alpha1.LolcowApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.Lolcow, err error) {
+	if lolcow == nil {
+		return nil, fmt.Errorf("lolcow provided to Apply must not be nil")
+	}
+	data, err := json.Marshal(lolcow)
+	if err != nil {
+		return nil, err
+	}
+	name := lolcow.Name
+	if name == nil {
+		return nil, fmt.Errorf("lolcow.Name must be provided to Apply")
+	}
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(lolcowsResource, c.ns, *name, types.ApplyPatchType, data), &v1alpha1.Lolcow{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.Lolcow), err
+}
+
+// ApplyStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+func (c *FakeLolcows) ApplyStatus(ctx context.Context, lolcow *lolcowv1alpha1.LolcowApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.Lolcow, err error) {
+	if lolcow == nil {
+		return nil, fmt.Errorf("lolcow provided to Apply must not be nil")
+	}
+	data, err := json.Marshal(lolcow)
+	if err != nil {
+		return nil, err
+	}
+	name := lolcow.Name
+	if name == nil {
+		return nil, fmt.Errorf("lolcow.Name must be provided to Apply")
+	}
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(lolcowsResource, c.ns, *name, types.ApplyPatchType, data, "status"), &v1alpha1.Lolcow{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.Lolcow), err
+}
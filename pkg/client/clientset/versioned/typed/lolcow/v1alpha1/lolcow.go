@@ -0,0 +1,255 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	json "encoding/json"
+	"fmt"
+	"time"
+	v1alpha1 "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+	lolcowv1alpha1 "vsoch/lolcow-operator/pkg/client/applyconfiguration/lolcow/v1alpha1"
+	scheme "vsoch/lolcow-operator/pkg/client/clientset/versioned/scheme"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// LolcowsGetter has a method to return a LolcowInterface.
+// A group's client should implement this interface.
+type LolcowsGetter interface {
+	Lolcows(namespace string) LolcowInterface
+}
+
+// LolcowInterface has methods to work with Lolcow resources.
+type LolcowInterface interface {
+	Create(ctx context.Context, lolcow *v1alpha1.Lolcow, opts v1.CreateOptions) (*v1alpha1.Lolcow, error)
+	Update(ctx context.Context, lolcow *v1alpha1.Lolcow, opts v1.UpdateOptions) (*v1alpha1.Lolcow, error)
+	UpdateStatus(ctx context.Context, lolcow *v1alpha1.Lolcow, opts v1.UpdateOptions) (*v1alpha1.Lolcow, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.Lolcow, error)
+	List(ctx context.Context, opts v1.ListOptions) (*v1alpha1.LolcowList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.Lolcow, err error)
+	Apply(ctx context.Context, lolcow *lolcowv1alpha1.LolcowApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.Lolcow, err error)
+	ApplyStatus(ctx context.Context, lolcow *lolcowv1alpha1.LolcowApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.Lolcow, err error)
+	LolcowExpansion
+}
+
+// lolcows implements LolcowInterface
+type lolcows struct {
+	client rest.Interface
+	ns     string
+}
+
+// newLolcows returns a Lolcows
+func newLolcows(c *LolcowV1alpha1Client, namespace string) *lolcows {
+	return &lolcows{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the lolcow, and returns the corresponding lolcow object, and an error if there is any.
+func (c *lolcows) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.Lolcow, err error) {
+	result = &v1alpha1.Lolcow{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("lolcows").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of Lolcows that match those selectors.
+func (c *lolcows) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.LolcowList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.LolcowList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("lolcows").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested lolcows.
+func (c *lolcows) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("lolcows").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a lolcow and creates it.  Returns the server's representation of the lolcow, and an error, if there is any.
+func (c *lolcows) Create(ctx context.Context, lolcow *v1alpha1.Lolcow, opts v1.CreateOptions) (result *v1alpha1.Lolcow, err error) {
+	result = &v1alpha1.Lolcow{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("lolcows").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(lolcow).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a lolcow and updates it. Returns the server's representation of the lolcow, and an error, if there is any.
+func (c *lolcows) Update(ctx context.Context, lolcow *v1alpha1.Lolcow, opts v1.UpdateOptions) (result *v1alpha1.Lolcow, err error) {
+	result = &v1alpha1.Lolcow{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("lolcows").
+		Name(lolcow.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(lolcow).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *lolcows) UpdateStatus(ctx context.Context, lolcow *v1alpha1.Lolcow, opts v1.UpdateOptions) (result *v1alpha1.Lolcow, err error) {
+	result = &v1alpha1.Lolcow{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("lolcows").
+		Name(lolcow.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(lolcow).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the lolcow and deletes it. Returns an error if one occurs.
+func (c *lolcows) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("lolcows").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *lolcows) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("lolcows").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched lolcow.
+func (c *lolcows) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.Lolcow, err error) {
+	result = &v1alpha1.Lolcow{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("lolcows").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Apply takes the given apply declarative configuration, applies it and returns the applied lolcow.
+func (c *lolcows) Apply(ctx context.Context, lolcow *lolcowv1alpha1.LolcowApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.Lolcow, err error) {
+	if lolcow == nil {
+		return nil, fmt.Errorf("lolcow provided to Apply must not be nil")
+	}
+	patchOpts := opts.ToPatchOptions()
+	data, err := json.Marshal(lolcow)
+	if err != nil {
+		return nil, err
+	}
+	name := lolcow.Name
+	if name == nil {
+		return nil, fmt.Errorf("lolcow.Name must be provided to Apply")
+	}
+	result = &v1alpha1.Lolcow{}
+	err = c.client.Patch(types.ApplyPatchType).
+		Namespace(c.ns).
+		Resource("lolcows").
+		Name(*name).
+		VersionedParams(&patchOpts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// ApplyStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+func (c *lolcows) ApplyStatus(ctx context.Context, lolcow *lolcowv1alpha1.LolcowApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.Lolcow, err error) {
+	if lolcow == nil {
+		return nil, fmt.Errorf("lolcow provided to Apply must not be nil")
+	}
+	patchOpts := opts.ToPatchOptions()
+	data, err := json.Marshal(lolcow)
+	if err != nil {
+		return nil, err
+	}
+
+	name := lolcow.Name
+	if name == nil {
+		return nil, fmt.Errorf("lolcow.Name must be provided to Apply")
+	}
+
+	result = &v1alpha1.Lolcow{}
+	err = c.client.Patch(types.ApplyPatchType).
+		Namespace(c.ns).
+		Resource("lolcows").
+		Name(*name).
+		SubResource("status").
+		VersionedParams(&patchOpts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
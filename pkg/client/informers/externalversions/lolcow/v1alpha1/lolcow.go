@@ -0,0 +1,89 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	time "time"
+	lolcowv1alpha1 "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+	versioned "vsoch/lolcow-operator/pkg/client/clientset/versioned"
+	internalinterfaces "vsoch/lolcow-operator/pkg/client/informers/externalversions/internalinterfaces"
+	v1alpha1 "vsoch/lolcow-operator/pkg/client/listers/lolcow/v1alpha1"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// LolcowInformer provides access to a shared informer and lister for
+// Lolcows.
+type LolcowInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1alpha1.LolcowLister
+}
+
+type lolcowInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+	namespace        string
+}
+
+// NewLolcowInformer constructs a new informer for Lolcow type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewLolcowInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredLolcowInformer(client, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredLolcowInformer constructs a new informer for Lolcow type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredLolcowInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.LolcowV1alpha1().Lolcows(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.LolcowV1alpha1().Lolcows(namespace).Watch(context.TODO(), options)
+			},
+		},
+		&lolcowv1alpha1.Lolcow{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *lolcowInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredLolcowInformer(client, f.namespace, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *lolcowInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&lolcowv1alpha1.Lolcow{}, f.defaultInformer)
+}
+
+func (f *lolcowInformer) Lister() v1alpha1.LolcowLister {
+	return v1alpha1.NewLolcowLister(f.Informer().GetIndexer())
+}
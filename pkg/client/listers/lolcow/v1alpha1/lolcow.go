@@ -0,0 +1,99 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// LolcowLister helps list Lolcows.
+// All objects returned here must be treated as read-only.
+type LolcowLister interface {
+	// List lists all Lolcows in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.Lolcow, err error)
+	// Lolcows returns an object that can list and get Lolcows.
+	Lolcows(namespace string) LolcowNamespaceLister
+	LolcowListerExpansion
+}
+
+// lolcowLister implements the LolcowLister interface.
+type lolcowLister struct {
+	indexer cache.Indexer
+}
+
+// NewLolcowLister returns a new LolcowLister.
+func NewLolcowLister(indexer cache.Indexer) LolcowLister {
+	return &lolcowLister{indexer: indexer}
+}
+
+// List lists all Lolcows in the indexer.
+func (s *lolcowLister) List(selector labels.Selector) (ret []*v1alpha1.Lolcow, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.Lolcow))
+	})
+	return ret, err
+}
+
+// Lolcows returns an object that can list and get Lolcows.
+func (s *lolcowLister) Lolcows(namespace string) LolcowNamespaceLister {
+	return lolcowNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// LolcowNamespaceLister helps list and get Lolcows.
+// All objects returned here must be treated as read-only.
+type LolcowNamespaceLister interface {
+	// List lists all Lolcows in the indexer for a given namespace.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.Lolcow, err error)
+	// Get retrieves the Lolcow from the indexer for a given namespace and name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v1alpha1.Lolcow, error)
+	LolcowNamespaceListerExpansion
+}
+
+// lolcowNamespaceLister implements the LolcowNamespaceLister
+// interface.
+type lolcowNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all Lolcows in the indexer for a given namespace.
+func (s lolcowNamespaceLister) List(selector labels.Selector) (ret []*v1alpha1.Lolcow, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.Lolcow))
+	})
+	return ret, err
+}
+
+// Get retrieves the Lolcow from the indexer for a given namespace and name.
+func (s lolcowNamespaceLister) Get(name string) (*v1alpha1.Lolcow, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("lolcow"), name)
+	}
+	return obj.(*v1alpha1.Lolcow), nil
+}
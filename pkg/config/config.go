@@ -0,0 +1,343 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/util/workqueue"
+	componentconfig "k8s.io/component-base/config/v1alpha1"
+	cfg "sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
+	"sigs.k8s.io/yaml"
+
+	"vsoch/lolcow-operator/api/config/v1alpha1"
+)
+
+// Defaults for anything the file (and flags) leave out
+const (
+	DefaultMetricsBindAddress      = ":8080"
+	DefaultHealthProbeBindAddress  = ":8081"
+	DefaultWebhookPort             = 9443
+	DefaultLeaderElectionID        = "9bf5ca16.my.domain"
+	DefaultImage                   = "ghcr.io/vsoch/lolcow-operator:latest"
+	DefaultGreeting                = "Hello from the Lolcow!"
+	DefaultServiceType             = corev1.ServiceTypeLoadBalancer
+	DefaultMaxConcurrentReconciles = 1
+	DefaultStuckQueueTimeout       = 15 * time.Minute
+)
+
+// Defaults for the webhook certificates the manager makes itself. The names
+// are the ones config/default gives the objects (with its namePrefix).
+const (
+	DefaultWebhookCertSecretName   = "lolcow-operator-webhook-certs"
+	DefaultWebhookServiceName      = "lolcow-operator-webhook-service"
+	DefaultMutatingWebhookConfig   = "lolcow-operator-mutating-webhook-configuration"
+	DefaultValidatingWebhookConfig = "lolcow-operator-validating-webhook-configuration"
+	DefaultLolcowCRD               = "lolcows.my.domain"
+	DefaultWebhookCertValidity     = 365 * 24 * time.Hour
+	minWebhookCertValidity         = time.Hour
+)
+
+// What to do about missing permissions at startup, see pkg/rbac
+const (
+	PermissionCheckFail    = "fail"
+	PermissionCheckDegrade = "degrade"
+	PermissionCheckOff     = "off"
+
+	DefaultPermissionCheck = PermissionCheckDegrade
+)
+
+// Defaults for the rate limiter, the same as controller-runtime's
+const (
+	DefaultRateLimiterBaseDelay = 5 * time.Millisecond
+	DefaultRateLimiterMaxDelay  = 1000 * time.Second
+	DefaultRateLimiterQPS       = 10
+	DefaultRateLimiterBurst     = 100
+)
+
+// Feature gates, and whether they are on by default
+const (
+
+	// NetworkPolicy lets lolcows ask for a NetworkPolicy. Turn it off on
+	// clusters without the networking.k8s.io API.
+	NetworkPolicy = "NetworkPolicy"
+
+	// Webhooks serves the defaulting and validating webhooks. This needs
+	// a serving certificate, see the [WEBHOOK] sections of config/default.
+	Webhooks = "Webhooks"
+)
+
+var defaultFeatureGates = map[string]bool{
+	NetworkPolicy: true,
+	Webhooks:      false,
+}
+
+// New returns a configuration with every default filled in
+func New() *v1alpha1.OperatorConfig {
+	leaderElect := false
+	webhookPort := DefaultWebhookPort
+	config := &v1alpha1.OperatorConfig{
+		ControllerManagerConfigurationSpec: cfg.ControllerManagerConfigurationSpec{
+			LeaderElection: &componentconfig.LeaderElectionConfiguration{
+				LeaderElect:  &leaderElect,
+				ResourceName: DefaultLeaderElectionID,
+			},
+			Metrics: cfg.ControllerMetrics{BindAddress: DefaultMetricsBindAddress},
+			Health:  cfg.ControllerHealth{HealthProbeBindAddress: DefaultHealthProbeBindAddress},
+			Webhook: cfg.ControllerWebhook{Port: &webhookPort},
+		},
+		Lolcow: v1alpha1.LolcowConfig{
+			MaxConcurrentReconciles: DefaultMaxConcurrentReconciles,
+			StuckQueueTimeout:       metav1.Duration{Duration: DefaultStuckQueueTimeout},
+			RateLimiter: v1alpha1.RateLimiterConfig{
+				BaseDelay: metav1.Duration{Duration: DefaultRateLimiterBaseDelay},
+				MaxDelay:  metav1.Duration{Duration: DefaultRateLimiterMaxDelay},
+				QPS:       DefaultRateLimiterQPS,
+				Burst:     DefaultRateLimiterBurst,
+			},
+			DefaultImage:       DefaultImage,
+			DefaultGreeting:    DefaultGreeting,
+			DefaultServiceType: DefaultServiceType,
+		},
+		FeatureGates:    map[string]bool{},
+		PermissionCheck: DefaultPermissionCheck,
+		WebhookCertificates: v1alpha1.WebhookCertificatesConfig{
+			SecretName:                      DefaultWebhookCertSecretName,
+			ServiceName:                     DefaultWebhookServiceName,
+			MutatingWebhookConfigurations:   []string{DefaultMutatingWebhookConfig},
+			ValidatingWebhookConfigurations: []string{DefaultValidatingWebhookConfig},
+			CustomResourceDefinitions:       []string{DefaultLolcowCRD},
+			Validity:                        metav1.Duration{Duration: DefaultWebhookCertValidity},
+		},
+	}
+	config.APIVersion = v1alpha1.GroupVersion.String()
+	config.Kind = v1alpha1.OperatorConfigKind
+	for name, enabled := range defaultFeatureGates {
+		config.FeatureGates[name] = enabled
+	}
+	return config
+}
+
+// Load reads the configuration file at path over the defaults
+// Unknown fields are an error, so a typo doesn't go unnoticed.
+func Load(path string) (*v1alpha1.OperatorConfig, error) {
+	config := New()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config file: %w", err)
+	}
+
+	// Check what the file is before decoding it (into the wrong thing)
+	var typeMeta struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+	}
+	if err := yaml.Unmarshal(content, &typeMeta); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if typeMeta.APIVersion != v1alpha1.GroupVersion.String() || typeMeta.Kind != v1alpha1.OperatorConfigKind {
+		return nil, fmt.Errorf("%s: expected apiVersion %s and kind %s, found apiVersion %q and kind %q",
+			path, v1alpha1.GroupVersion, v1alpha1.OperatorConfigKind, typeMeta.APIVersion, typeMeta.Kind)
+	}
+	if err := yaml.UnmarshalStrict(content, config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Validate checks the configuration, returning every problem at once
+func Validate(config *v1alpha1.OperatorConfig) error {
+	errs := field.ErrorList{}
+
+	lolcow := field.NewPath("lolcow")
+	if config.Lolcow.MaxConcurrentReconciles < 1 {
+		errs = append(errs, field.Invalid(lolcow.Child("maxConcurrentReconciles"),
+			config.Lolcow.MaxConcurrentReconciles, "must be at least 1"))
+	}
+	errs = append(errs, validateRateLimiter(lolcow.Child("rateLimiter"), config.Lolcow.RateLimiter)...)
+	if timeout := config.Lolcow.StuckQueueTimeout; timeout.Duration < 0 {
+		errs = append(errs, field.Invalid(lolcow.Child("stuckQueueTimeout"), timeout.Duration.String(), "must be at least 0"))
+	}
+	if config.Lolcow.DefaultImage == "" {
+		errs = append(errs, field.Required(lolcow.Child("defaultImage"), ""))
+	} else if strings.ContainsAny(config.Lolcow.DefaultImage, " \t\n") {
+		errs = append(errs, field.Invalid(lolcow.Child("defaultImage"),
+			config.Lolcow.DefaultImage, "must not contain whitespace"))
+	}
+	switch config.Lolcow.DefaultServiceType {
+	case corev1.ServiceTypeLoadBalancer, corev1.ServiceTypeNodePort, corev1.ServiceTypeClusterIP:
+	default:
+		errs = append(errs, field.NotSupported(lolcow.Child("defaultServiceType"),
+			config.Lolcow.DefaultServiceType, []string{
+				string(corev1.ServiceTypeLoadBalancer),
+				string(corev1.ServiceTypeNodePort),
+				string(corev1.ServiceTypeClusterIP),
+			}))
+	}
+	for i, namespace := range config.Lolcow.WatchNamespaces {
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			errs = append(errs, field.Invalid(lolcow.Child("watchNamespaces").Index(i), namespace, msg))
+		}
+	}
+
+	errs = append(errs, validateAddress(field.NewPath("metrics", "bindAddress"), config.Metrics.BindAddress)...)
+	errs = append(errs, validateAddress(field.NewPath("health", "healthProbeBindAddress"), config.Health.HealthProbeBindAddress)...)
+	if address := config.Metrics.BindAddress; config.SecureMetrics.Enabled && (address == "" || address == "0") {
+		errs = append(errs, field.Invalid(field.NewPath("secureMetrics", "enabled"), true, "needs metrics.bindAddress"))
+	}
+	if port := config.Webhook.Port; port != nil && (*port < 1 || *port > 65535) {
+		errs = append(errs, field.Invalid(field.NewPath("webhook", "port"), *port, "must be between 1 and 65535"))
+	}
+	if election := config.LeaderElection; election != nil && election.LeaderElect != nil && *election.LeaderElect {
+		if election.ResourceName == "" {
+			errs = append(errs, field.Required(field.NewPath("leaderElection", "resourceName"), "needed for leader election"))
+		}
+	}
+
+	errs = append(errs, validateWebhookCertificates(field.NewPath("webhookCertificates"), config)...)
+	switch config.PermissionCheck {
+	case PermissionCheckFail, PermissionCheckDegrade, PermissionCheckOff:
+	default:
+		errs = append(errs, field.NotSupported(field.NewPath("permissionCheck"), config.PermissionCheck,
+			[]string{PermissionCheckFail, PermissionCheckDegrade, PermissionCheckOff}))
+	}
+
+	known := []string{}
+	for name := range defaultFeatureGates {
+		known = append(known, name)
+	}
+	sort.Strings(known)
+	for name := range config.FeatureGates {
+		if _, ok := defaultFeatureGates[name]; !ok {
+			errs = append(errs, field.NotSupported(field.NewPath("featureGates").Key(name), name, known))
+		}
+	}
+	return errs.ToAggregate()
+}
+
+// validateWebhookCertificates checks the names and validity, if the
+// manager makes its own certificates
+func validateWebhookCertificates(path *field.Path, config *v1alpha1.OperatorConfig) field.ErrorList {
+	certs := config.WebhookCertificates
+	if !certs.SelfManaged {
+		return nil
+	}
+	errs := field.ErrorList{}
+	if !Enabled(config.FeatureGates, Webhooks) {
+		errs = append(errs, field.Invalid(path.Child("selfManaged"), true, "needs the Webhooks feature gate"))
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(certs.SecretName) {
+		errs = append(errs, field.Invalid(path.Child("secretName"), certs.SecretName, msg))
+	}
+	for _, msg := range validation.IsDNS1035Label(certs.ServiceName) {
+		errs = append(errs, field.Invalid(path.Child("serviceName"), certs.ServiceName, msg))
+	}
+	if certs.Validity.Duration < minWebhookCertValidity {
+		errs = append(errs, field.Invalid(path.Child("validity"), certs.Validity.Duration.String(),
+			"must be at least "+minWebhookCertValidity.String()))
+	}
+	return errs
+}
+
+// validateRateLimiter checks the delays and the bucket are usable
+func validateRateLimiter(path *field.Path, limiter v1alpha1.RateLimiterConfig) field.ErrorList {
+	errs := field.ErrorList{}
+	if limiter.BaseDelay.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("baseDelay"), limiter.BaseDelay.Duration.String(), "must be more than 0"))
+	}
+	if limiter.MaxDelay.Duration < limiter.BaseDelay.Duration {
+		errs = append(errs, field.Invalid(path.Child("maxDelay"), limiter.MaxDelay.Duration.String(), "must be at least baseDelay"))
+	}
+	if limiter.QPS <= 0 {
+		errs = append(errs, field.Invalid(path.Child("qps"), limiter.QPS, "must be more than 0"))
+	}
+	if limiter.Burst < 1 {
+		errs = append(errs, field.Invalid(path.Child("burst"), limiter.Burst, "must be at least 1"))
+	}
+	return errs
+}
+
+// NewRateLimiter makes the workqueue rate limiter from the configuration
+// Like controller-runtime's default, a failed item waits for the longer of
+// its exponential backoff and the token bucket (shared by all items).
+func NewRateLimiter(limiter v1alpha1.RateLimiterConfig) workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(limiter.BaseDelay.Duration, limiter.MaxDelay.Duration),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(limiter.QPS), limiter.Burst)},
+	)
+}
+
+// validateAddress checks a bind address, "0" turns it off
+func validateAddress(path *field.Path, address string) field.ErrorList {
+	if address == "" || address == "0" {
+		return nil
+	}
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, address, "must be host:port, :port, or 0 to disable")}
+	}
+	if number, err := strconv.Atoi(port); err != nil || number < 0 || number > 65535 {
+		return field.ErrorList{field.Invalid(path, address, "port must be between 0 and 65535")}
+	}
+	return nil
+}
+
+// Enabled says if a feature gate is on, given the gates that were set
+func Enabled(gates map[string]bool, name string) bool {
+	if enabled, ok := gates[name]; ok {
+		return enabled
+	}
+	return defaultFeatureGates[name]
+}
+
+// ParseFeatureGates reads --feature-gates=Name=true,Other=false into config
+func ParseFeatureGates(config *v1alpha1.OperatorConfig, value string) error {
+	errs := []error{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			errs = append(errs, fmt.Errorf("feature gate %q must be Name=true or Name=false", pair))
+			continue
+		}
+		enabled, err := strconv.ParseBool(parts[1])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("feature gate %q must be Name=true or Name=false", pair))
+			continue
+		}
+		if config.FeatureGates == nil {
+			config.FeatureGates = map[string]bool{}
+		}
+		config.FeatureGates[strings.TrimSpace(parts[0])] = enabled
+	}
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"vsoch/lolcow-operator/api/config/v1alpha1"
+)
+
+const header = "apiVersion: config.my.domain/v1alpha1\nkind: OperatorConfig\n"
+
+// writeConfig writes a configuration file in dir, and returns its path
+func writeConfig(dir, content string) string {
+	path := filepath.Join(dir, "config.yaml")
+	Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+	return path
+}
+
+var _ = Describe("Config", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "lolcow-config")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("loads and validates the sample in config/manager", func() {
+		config, err := Load("../../config/manager/controller_manager_config.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Validate(config)).To(Succeed())
+		Expect(*config.LeaderElection.LeaderElect).To(BeTrue())
+		Expect(config.Metrics.BindAddress).To(Equal("127.0.0.1:8080"))
+	})
+
+	It("validates the defaults", func() {
+		Expect(Validate(New())).To(Succeed())
+	})
+
+	DescribeTable("Load",
+		func(content, expected string, check func(*v1alpha1.OperatorConfig)) {
+			config, err := Load(writeConfig(dir, content))
+			if expected != "" {
+				Expect(err).To(MatchError(ContainSubstring(expected)))
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			check(config)
+		},
+		Entry("only the type, all defaults", header, "", func(config *v1alpha1.OperatorConfig) {
+			Expect(config).To(Equal(New()))
+		}),
+		Entry("a few fields, over the defaults", header+"lolcow:\n  defaultGreeting: Moo\n  rateLimiter:\n    qps: 2\n", "",
+			func(config *v1alpha1.OperatorConfig) {
+				Expect(config.Lolcow.DefaultGreeting).To(Equal("Moo"))
+				Expect(config.Lolcow.RateLimiter.QPS).To(Equal(2.0))
+				Expect(config.Lolcow.RateLimiter.Burst).To(Equal(DefaultRateLimiterBurst))
+				Expect(config.Lolcow.DefaultImage).To(Equal(DefaultImage))
+			}),
+		Entry("feature gates, merged with the defaults", header+"featureGates:\n  Webhooks: true\n", "",
+			func(config *v1alpha1.OperatorConfig) {
+				Expect(config.FeatureGates).To(Equal(map[string]bool{NetworkPolicy: true, Webhooks: true}))
+			}),
+		Entry("a wrong apiVersion", "apiVersion: config.my.domain/v1\nkind: OperatorConfig\n",
+			`found apiVersion "config.my.domain/v1" and kind "OperatorConfig"`, nil),
+		Entry("a wrong kind", "apiVersion: config.my.domain/v1alpha1\nkind: ControllerManagerConfig\n",
+			`found apiVersion "config.my.domain/v1alpha1" and kind "ControllerManagerConfig"`, nil),
+		Entry("no type at all", "lolcow:\n  defaultGreeting: Moo\n", `found apiVersion "" and kind ""`, nil),
+		Entry("an unknown field", header+"lolcow:\n  defaultGreting: Moo\n", `unknown field "defaultGreting"`, nil),
+		Entry("a field of the wrong type", header+"lolcow:\n  maxConcurrentReconciles: many\n", "cannot unmarshal", nil),
+		Entry("a bad duration", header+"lolcow:\n  stuckQueueTimeout: soon\n", "soon", nil),
+		Entry("not YAML", "apiVersion: [", "config.yaml", nil),
+	)
+
+	It("fails on a missing file", func() {
+		_, err := Load(filepath.Join(dir, "missing.yaml"))
+		Expect(err).To(MatchError(ContainSubstring("cannot read config file")))
+	})
+
+	DescribeTable("Validate",
+		func(change func(*v1alpha1.OperatorConfig), expected ...string) {
+			config := New()
+			change(config)
+			err := Validate(config)
+			if len(expected) == 0 {
+				Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			Expect(err).To(HaveOccurred())
+			for _, message := range expected {
+				Expect(err.Error()).To(ContainSubstring(message))
+			}
+		},
+		Entry("an unknown feature gate", func(config *v1alpha1.OperatorConfig) {
+			config.FeatureGates["NetworkPolicies"] = true
+		}, `featureGates[NetworkPolicies]: Unsupported value: "NetworkPolicies": supported values: "NetworkPolicy", "Webhooks"`),
+		Entry("a known feature gate turned off", func(config *v1alpha1.OperatorConfig) {
+			config.FeatureGates[NetworkPolicy] = false
+		}),
+		Entry("no base delay", func(config *v1alpha1.OperatorConfig) {
+			config.Lolcow.RateLimiter.BaseDelay.Duration = 0
+		}, "lolcow.rateLimiter.baseDelay: Invalid value: \"0s\": must be more than 0"),
+		Entry("a max delay under the base delay", func(config *v1alpha1.OperatorConfig) {
+			config.Lolcow.RateLimiter.MaxDelay.Duration = time.Millisecond
+		}, "lolcow.rateLimiter.maxDelay: Invalid value: \"1ms\": must be at least baseDelay"),
+		Entry("no qps", func(config *v1alpha1.OperatorConfig) {
+			config.Lolcow.RateLimiter.QPS = 0
+		}, "lolcow.rateLimiter.qps: Invalid value: 0: must be more than 0"),
+		Entry("a negative qps", func(config *v1alpha1.OperatorConfig) {
+			config.Lolcow.RateLimiter.QPS = -1
+		}, "lolcow.rateLimiter.qps"),
+		Entry("no burst", func(config *v1alpha1.OperatorConfig) {
+			config.Lolcow.RateLimiter.Burst = 0
+		}, "lolcow.rateLimiter.burst: Invalid value: 0: must be at least 1"),
+		Entry("no concurrent reconciles", func(config *v1alpha1.OperatorConfig) {
+			config.Lolcow.MaxConcurrentReconciles = 0
+		}, "lolcow.maxConcurrentReconciles"),
+		Entry("a negative stuck queue timeout", func(config *v1alpha1.OperatorConfig) {
+			config.Lolcow.StuckQueueTimeout.Duration = -time.Second
+		}, "lolcow.stuckQueueTimeout"),
+		Entry("no stuck queue timeout", func(config *v1alpha1.OperatorConfig) {
+			config.Lolcow.StuckQueueTimeout.Duration = 0
+		}),
+		Entry("an image with spaces", func(config *v1alpha1.OperatorConfig) {
+			config.Lolcow.DefaultImage = "lolcow latest"
+		}, "lolcow.defaultImage"),
+		Entry("an unknown service type", func(config *v1alpha1.OperatorConfig) {
+			config.Lolcow.DefaultServiceType = corev1.ServiceTypeExternalName
+		}, "lolcow.defaultServiceType: Unsupported value"),
+		Entry("a bad namespace", func(config *v1alpha1.OperatorConfig) {
+			config.Lolcow.WatchNamespaces = []string{"team-a", "Team_B"}
+		}, "lolcow.watchNamespaces[1]"),
+		Entry("a bad bind address", func(config *v1alpha1.OperatorConfig) {
+			config.Metrics.BindAddress = "8080"
+		}, "metrics.bindAddress"),
+		Entry("metrics turned off", func(config *v1alpha1.OperatorConfig) {
+			config.Metrics.BindAddress = "0"
+		}),
+		Entry("secure metrics turned off", func(config *v1alpha1.OperatorConfig) {
+			config.Metrics.BindAddress = "0"
+			config.SecureMetrics.Enabled = true
+		}, "secureMetrics.enabled"),
+		Entry("an unknown permission check", func(config *v1alpha1.OperatorConfig) {
+			config.PermissionCheck = "warn"
+		}, "permissionCheck: Unsupported value"),
+		Entry("self managed certificates without webhooks", func(config *v1alpha1.OperatorConfig) {
+			config.WebhookCertificates.SelfManaged = true
+		}, "webhookCertificates.selfManaged"),
+		Entry("every problem at once", func(config *v1alpha1.OperatorConfig) {
+			config.FeatureGates["Moo"] = true
+			config.Lolcow.RateLimiter.Burst = 0
+			config.PermissionCheck = "warn"
+		}, "featureGates[Moo]", "lolcow.rateLimiter.burst", "permissionCheck"),
+	)
+})
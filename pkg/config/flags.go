@@ -0,0 +1,197 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"flag"
+	"os"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"vsoch/lolcow-operator/api/config/v1alpha1"
+)
+
+// Flags are the command line flags that override the configuration file
+// Only flags given on the command line override it (a default doesn't).
+type Flags struct {
+	fs *flag.FlagSet
+
+	// File is the path to the configuration file, if any
+	File string
+
+	metricsAddr             string
+	probeAddr               string
+	enableLeaderElection    bool
+	labelPrefixes           string
+	annotationPrefixes      string
+	watchNamespaces         string
+	maxConcurrentReconciles int
+	rateLimiterBaseDelay    time.Duration
+	rateLimiterMaxDelay     time.Duration
+	rateLimiterQPS          float64
+	rateLimiterBurst        int
+	defaultImage            string
+	defaultGreeting         string
+	defaultServiceType      string
+	featureGates            string
+	controllers             string
+	webhookCertSelfManaged  bool
+	webhookCertSecret       string
+	webhookCertValidity     time.Duration
+	metricsSecure           bool
+	metricsCertDir          string
+	stuckQueueTimeout       time.Duration
+	permissionCheck         string
+}
+
+// BindFlags adds the configuration flags to a flag set
+func BindFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{fs: fs}
+	fs.StringVar(&f.File, "config", "",
+		"The operator configuration file (an OperatorConfig). Flags given on the command line override it.")
+	fs.StringVar(&f.metricsAddr, "metrics-bind-address", DefaultMetricsBindAddress, "The address the metric endpoint binds to.")
+	fs.BoolVar(&f.metricsSecure, "metrics-secure", false,
+		"Serve the metrics over HTTPS, to those allowed to get /metrics, instead of plain HTTP.")
+	fs.StringVar(&f.metricsCertDir, "metrics-cert-dir", "",
+		"The directory with the HTTPS metrics certificate (tls.crt and tls.key). Defaults to a self signed one.")
+	fs.StringVar(&f.probeAddr, "health-probe-bind-address", DefaultHealthProbeBindAddress, "The address the probe endpoint binds to.")
+	fs.BoolVar(&f.enableLeaderElection, "leader-elect", false,
+		"Enable leader election for controller manager. "+
+			"Enabling this will ensure there is only one active controller manager.")
+	fs.StringVar(&f.labelPrefixes, "propagate-label-prefixes", "",
+		"Comma separated prefixes of Lolcow labels to copy to the Deployment, pods and Service (e.g., team.example.com/).")
+	fs.StringVar(&f.annotationPrefixes, "propagate-annotation-prefixes", "",
+		"Comma separated prefixes of Lolcow annotations to copy to the Deployment, pods and Service.")
+	fs.StringVar(&f.watchNamespaces, "watch-namespaces", "",
+		"Comma separated namespaces to watch. Defaults to WATCH_NAMESPACE, and then all namespaces.")
+	fs.IntVar(&f.maxConcurrentReconciles, "max-concurrent-reconciles", DefaultMaxConcurrentReconciles,
+		"How many lolcows can be reconciled at once.")
+	fs.DurationVar(&f.stuckQueueTimeout, "stuck-queue-timeout", DefaultStuckQueueTimeout,
+		"Fail the liveness probe when lolcows are pending, and no reconcile finished for this long. 0 turns it off.")
+	fs.DurationVar(&f.rateLimiterBaseDelay, "rate-limiter-base-delay", DefaultRateLimiterBaseDelay,
+		"The first retry delay of a failed lolcow, doubled on each failure.")
+	fs.DurationVar(&f.rateLimiterMaxDelay, "rate-limiter-max-delay", DefaultRateLimiterMaxDelay,
+		"The longest retry delay of a failed lolcow.")
+	fs.Float64Var(&f.rateLimiterQPS, "rate-limiter-qps", DefaultRateLimiterQPS,
+		"How many retries per second are allowed, over all lolcows.")
+	fs.IntVar(&f.rateLimiterBurst, "rate-limiter-burst", DefaultRateLimiterBurst,
+		"How many retries can go over --rate-limiter-qps at once.")
+	fs.StringVar(&f.defaultImage, "default-image", DefaultImage, "The image that serves the greeting.")
+	fs.StringVar(&f.defaultGreeting, "default-greeting", DefaultGreeting, "The greeting of lolcows that don't have one.")
+	fs.StringVar(&f.defaultServiceType, "default-service-type", string(DefaultServiceType),
+		"The type of the lolcow Services: LoadBalancer, NodePort or ClusterIP.")
+	fs.StringVar(&f.featureGates, "feature-gates", "",
+		"Comma separated feature gates to turn on or off, e.g., NetworkPolicy=false.")
+	fs.StringVar(&f.controllers, "controllers", "*",
+		"Comma separated controllers to run. '*' is all of them, 'foo' turns on foo, and '-foo' turns off foo.")
+	fs.BoolVar(&f.webhookCertSelfManaged, "webhook-cert-self-managed", false,
+		"Make, rotate and inject the webhook certificates, instead of cert-manager.")
+	fs.StringVar(&f.webhookCertSecret, "webhook-cert-secret", DefaultWebhookCertSecretName,
+		"The Secret (in the manager's namespace) that keeps the self managed webhook certificates.")
+	fs.DurationVar(&f.webhookCertValidity, "webhook-cert-validity", DefaultWebhookCertValidity,
+		"How long a self managed webhook certificate lasts. It's renewed when a third is left.")
+	fs.StringVar(&f.permissionCheck, "permission-check", DefaultPermissionCheck,
+		"What to do when the manager misses a permission at startup: fail, degrade (turn off the feature gates that need it) or off.")
+	return f
+}
+
+// Load reads the configuration file (or the defaults, without one), and
+// applies the flags given on the command line. It doesn't validate.
+func (f *Flags) Load() (*v1alpha1.OperatorConfig, error) {
+	config := New()
+	if f.File != "" {
+		var err error
+		config, err = Load(f.File)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The environment is between the file and the flags
+	if namespaces := os.Getenv("WATCH_NAMESPACE"); namespaces != "" {
+		config.Lolcow.WatchNamespaces = SplitList(namespaces)
+	}
+
+	var err error
+	f.fs.Visit(func(set *flag.Flag) {
+		switch set.Name {
+		case "metrics-bind-address":
+			config.Metrics.BindAddress = f.metricsAddr
+		case "metrics-secure":
+			config.SecureMetrics.Enabled = f.metricsSecure
+		case "metrics-cert-dir":
+			config.SecureMetrics.CertDir = f.metricsCertDir
+		case "health-probe-bind-address":
+			config.Health.HealthProbeBindAddress = f.probeAddr
+		case "leader-elect":
+			if config.LeaderElection == nil {
+				config.LeaderElection = New().LeaderElection
+			}
+			config.LeaderElection.LeaderElect = &f.enableLeaderElection
+		case "propagate-label-prefixes":
+			config.Lolcow.PropagateLabelPrefixes = SplitList(f.labelPrefixes)
+		case "propagate-annotation-prefixes":
+			config.Lolcow.PropagateAnnotationPrefixes = SplitList(f.annotationPrefixes)
+		case "watch-namespaces":
+			config.Lolcow.WatchNamespaces = SplitList(f.watchNamespaces)
+		case "max-concurrent-reconciles":
+			config.Lolcow.MaxConcurrentReconciles = f.maxConcurrentReconciles
+		case "stuck-queue-timeout":
+			config.Lolcow.StuckQueueTimeout.Duration = f.stuckQueueTimeout
+		case "rate-limiter-base-delay":
+			config.Lolcow.RateLimiter.BaseDelay.Duration = f.rateLimiterBaseDelay
+		case "rate-limiter-max-delay":
+			config.Lolcow.RateLimiter.MaxDelay.Duration = f.rateLimiterMaxDelay
+		case "rate-limiter-qps":
+			config.Lolcow.RateLimiter.QPS = f.rateLimiterQPS
+		case "rate-limiter-burst":
+			config.Lolcow.RateLimiter.Burst = f.rateLimiterBurst
+		case "default-image":
+			config.Lolcow.DefaultImage = f.defaultImage
+		case "default-greeting":
+			config.Lolcow.DefaultGreeting = f.defaultGreeting
+		case "default-service-type":
+			config.Lolcow.DefaultServiceType = corev1.ServiceType(f.defaultServiceType)
+		case "controllers":
+			config.Controllers = SplitList(f.controllers)
+		case "webhook-cert-self-managed":
+			config.WebhookCertificates.SelfManaged = f.webhookCertSelfManaged
+		case "webhook-cert-secret":
+			config.WebhookCertificates.SecretName = f.webhookCertSecret
+		case "webhook-cert-validity":
+			config.WebhookCertificates.Validity.Duration = f.webhookCertValidity
+		case "permission-check":
+			config.PermissionCheck = f.permissionCheck
+		case "feature-gates":
+			err = ParseFeatureGates(config, f.featureGates)
+		}
+	})
+	return config, err
+}
+
+// SplitList parses a comma separated flag, dropping empty entries
+func SplitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"vsoch/lolcow-operator/api/config/v1alpha1"
+)
+
+var _ = Describe("Flags", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "lolcow-flags")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Unsetenv("WATCH_NAMESPACE")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+		Expect(os.Unsetenv("WATCH_NAMESPACE")).To(Succeed())
+	})
+
+	// load parses the arguments, with the file (if any) first
+	load := func(file string, args ...string) (*v1alpha1.OperatorConfig, error) {
+		fs := flag.NewFlagSet("manager", flag.ContinueOnError)
+		f := BindFlags(fs)
+		if file != "" {
+			args = append([]string{"--config", writeConfig(dir, file)}, args...)
+		}
+		Expect(fs.Parse(args)).To(Succeed())
+		return f.Load()
+	}
+
+	const file = header + `metrics:
+  bindAddress: 127.0.0.1:8080
+leaderElection:
+  leaderElect: true
+  resourceName: lolcow
+lolcow:
+  maxConcurrentReconciles: 4
+  defaultGreeting: Moo
+  watchNamespaces: [from-file]
+  rateLimiter:
+    baseDelay: 10ms
+    maxDelay: 10s
+    qps: 2
+    burst: 20
+featureGates:
+  NetworkPolicy: false
+`
+
+	It("is the defaults without a file or flags", func() {
+		config, err := load("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(Equal(New()))
+	})
+
+	It("leaves the file alone when no flags are given", func() {
+		config, err := load(file)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Metrics.BindAddress).To(Equal("127.0.0.1:8080"))
+		Expect(*config.LeaderElection.LeaderElect).To(BeTrue())
+		Expect(config.Lolcow.MaxConcurrentReconciles).To(Equal(4))
+		Expect(config.Lolcow.RateLimiter.QPS).To(Equal(2.0))
+		Expect(config.FeatureGates[NetworkPolicy]).To(BeFalse())
+	})
+
+	DescribeTable("flags over the file",
+		func(arg string, check func(*v1alpha1.OperatorConfig)) {
+			config, err := load(file, arg)
+			Expect(err).NotTo(HaveOccurred())
+			check(config)
+		},
+		Entry("metrics", "--metrics-bind-address=:9090", func(config *v1alpha1.OperatorConfig) {
+			Expect(config.Metrics.BindAddress).To(Equal(":9090"))
+		}),
+		Entry("leader election", "--leader-elect=false", func(config *v1alpha1.OperatorConfig) {
+			Expect(*config.LeaderElection.LeaderElect).To(BeFalse())
+			Expect(config.LeaderElection.ResourceName).To(Equal("lolcow"))
+		}),
+		Entry("concurrency", "--max-concurrent-reconciles=8", func(config *v1alpha1.OperatorConfig) {
+			Expect(config.Lolcow.MaxConcurrentReconciles).To(Equal(8))
+		}),
+		Entry("the greeting", "--default-greeting=Baa", func(config *v1alpha1.OperatorConfig) {
+			Expect(config.Lolcow.DefaultGreeting).To(Equal("Baa"))
+		}),
+		Entry("the namespaces", "--watch-namespaces=team-a, team-b,", func(config *v1alpha1.OperatorConfig) {
+			Expect(config.Lolcow.WatchNamespaces).To(Equal([]string{"team-a", "team-b"}))
+		}),
+		Entry("the base delay, and only that", "--rate-limiter-base-delay=1s", func(config *v1alpha1.OperatorConfig) {
+			Expect(config.Lolcow.RateLimiter.BaseDelay.Duration).To(Equal(time.Second))
+			Expect(config.Lolcow.RateLimiter.MaxDelay.Duration).To(Equal(10 * time.Second))
+			Expect(config.Lolcow.RateLimiter.QPS).To(Equal(2.0))
+			Expect(config.Lolcow.RateLimiter.Burst).To(Equal(20))
+		}),
+		Entry("the qps", "--rate-limiter-qps=0.5", func(config *v1alpha1.OperatorConfig) {
+			Expect(config.Lolcow.RateLimiter.QPS).To(Equal(0.5))
+		}),
+		Entry("the burst", "--rate-limiter-burst=5", func(config *v1alpha1.OperatorConfig) {
+			Expect(config.Lolcow.RateLimiter.Burst).To(Equal(5))
+		}),
+		Entry("a feature gate, keeping the others", "--feature-gates=Webhooks=true", func(config *v1alpha1.OperatorConfig) {
+			Expect(config.FeatureGates).To(Equal(map[string]bool{NetworkPolicy: false, Webhooks: true}))
+		}),
+		Entry("the controllers", "--controllers=-lolcow", func(config *v1alpha1.OperatorConfig) {
+			Expect(config.Controllers).To(Equal([]string{"-lolcow"}))
+		}),
+	)
+
+	It("puts WATCH_NAMESPACE between the file and the flags", func() {
+		Expect(os.Setenv("WATCH_NAMESPACE", "from-env")).To(Succeed())
+		config, err := load(file)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Lolcow.WatchNamespaces).To(Equal([]string{"from-env"}))
+
+		config, err = load(file, "--watch-namespaces=from-flag")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Lolcow.WatchNamespaces).To(Equal([]string{"from-flag"}))
+	})
+
+	It("turns on leader election without one in the file", func() {
+		config, err := load(header+"leaderElection: null\n", "--leader-elect")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*config.LeaderElection.LeaderElect).To(BeTrue())
+		Expect(config.LeaderElection.ResourceName).To(Equal(DefaultLeaderElectionID))
+	})
+
+	It("fails on a bad file", func() {
+		_, err := load("kind: Deployment\n", "--default-greeting=Baa")
+		Expect(err).To(MatchError(ContainSubstring("expected apiVersion")))
+	})
+
+	DescribeTable("bad feature gates",
+		func(value string) {
+			_, err := load("", "--feature-gates="+value)
+			Expect(err).To(MatchError(ContainSubstring("must be Name=true or Name=false")))
+		},
+		Entry("no value", "NetworkPolicy"),
+		Entry("not a bool", "NetworkPolicy=maybe"),
+		Entry("one bad among good ones", "Webhooks=true,NetworkPolicy"),
+	)
+
+	It("leaves unknown feature gates to Validate", func() {
+		config, err := load("", "--feature-gates=Moo=true")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Validate(config)).To(MatchError(ContainSubstring("featureGates[Moo]")))
+	})
+
+	It("leaves invalid values to Validate", func() {
+		config, err := load(file, "--rate-limiter-qps=0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Validate(config)).To(MatchError(ContainSubstring("lolcow.rateLimiter.qps")))
+	})
+})
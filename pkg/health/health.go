@@ -0,0 +1,67 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// The readiness checks of the manager. It's ready once its caches are
+// synced, the lolcow API is served and (with webhooks) the webhook server
+// answers TLS, so the Service only sends requests to a manager that can
+// handle them.
+
+// ProbeTimeout is how long a check waits, the kubelet's default probe timeout
+const ProbeTimeout = time.Second
+
+// CacheSynced is ready once every informer the manager started has synced
+func CacheSynced(c cache.Cache) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), ProbeTimeout)
+		defer cancel()
+		if !c.WaitForCacheSync(ctx) {
+			return errors.New("the caches haven't synced yet")
+		}
+		return nil
+	}
+}
+
+// ResourceServed is ready while the API server serves the resource, e.g.,
+// while the lolcow CRD is installed
+func ResourceServed(client discovery.DiscoveryInterface, resource schema.GroupVersionResource) healthz.Checker {
+	return func(_ *http.Request) error {
+		resources, err := client.ServerResourcesForGroupVersion(resource.GroupVersion().String())
+		if err != nil {
+			return fmt.Errorf("cannot find %s, is the CRD installed? %w", resource.GroupResource(), err)
+		}
+		for _, served := range resources.APIResources {
+			if served.Name == resource.Resource {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s is not served, is the CRD installed?", resource.GroupResource())
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Watchdog", func() {
+	var (
+		watchdog *Watchdog
+		depth    float64
+		now      time.Time
+	)
+
+	check := func() error {
+		return watchdog.Check(httptest.NewRequest("GET", "/healthz", nil))
+	}
+
+	// reconcile runs a reconcile through the watchdog, which takes a minute
+	reconcileOnce := func() {
+		_, err := watchdog.Wrap(reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
+			now = now.Add(time.Minute)
+			return reconcile.Result{}, nil
+		})).Reconcile(context.Background(), reconcile.Request{})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		depth = 0
+		now = time.Now()
+		watchdog = &Watchdog{
+			Name:    "lolcow",
+			Timeout: 10 * time.Minute,
+			depth:   func() (float64, error) { return depth, nil },
+			now:     func() time.Time { return now },
+		}
+	})
+
+	It("is happy with an empty queue, however long", func() {
+		Expect(check()).To(Succeed())
+		now = now.Add(time.Hour)
+		Expect(check()).To(Succeed())
+	})
+
+	It("gives pending lolcows until the timeout", func() {
+		now = now.Add(time.Hour)
+		depth = 3
+		Expect(check()).To(Succeed())
+		now = now.Add(9 * time.Minute)
+		Expect(check()).To(Succeed())
+		now = now.Add(2 * time.Minute)
+		Expect(check()).To(MatchError(ContainSubstring("the lolcow workqueue is stuck: 3 queued and 0 reconciling")))
+	})
+
+	It("is happy while reconciles finish", func() {
+		depth = 3
+		Expect(check()).To(Succeed())
+		for i := 0; i < 30; i++ {
+			reconcileOnce()
+			Expect(check()).To(Succeed())
+		}
+	})
+
+	It("starts over once the queue is empty", func() {
+		depth = 1
+		Expect(check()).To(Succeed())
+		now = now.Add(9 * time.Minute)
+		depth = 0
+		Expect(check()).To(Succeed())
+		depth = 1
+		Expect(check()).To(Succeed())
+		now = now.Add(9 * time.Minute)
+		Expect(check()).To(Succeed())
+	})
+
+	It("notices a reconcile that hangs", func() {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			watchdog.Wrap(reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
+				close(started)
+				<-release
+				return reconcile.Result{}, nil
+			})).Reconcile(context.Background(), reconcile.Request{})
+		}()
+		<-started
+
+		Expect(check()).To(Succeed())
+		now = now.Add(11 * time.Minute)
+		Expect(check()).To(MatchError(ContainSubstring("0 queued and 1 reconciling")))
+		close(release)
+		<-done
+		Expect(check()).To(Succeed())
+	})
+
+	It("reads the depth of a controller-runtime workqueue", func() {
+		queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "health-test")
+		defer queue.ShutDown()
+		queue.Add("a")
+		queue.Add("b")
+
+		depth, err := queueDepth(metrics.Registry, "health-test")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(depth()).To(Equal(2.0))
+
+		other, err := queueDepth(metrics.Registry, "not-made-yet")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other()).To(Equal(0.0))
+	})
+})
+
+var _ = Describe("Readiness", func() {
+	request := httptest.NewRequest("GET", "/readyz", nil)
+
+	It("waits for the caches to sync", func() {
+		synced := false
+		informers := &informertest.FakeInformers{Synced: &synced}
+		Expect(CacheSynced(informers)(request)).To(MatchError(ContainSubstring("haven't synced")))
+		synced = true
+		Expect(CacheSynced(informers)(request)).To(Succeed())
+	})
+
+	It("needs the lolcow API", func() {
+		lolcows := schema.GroupVersionResource{Group: "my.domain", Version: "v1alpha1", Resource: "lolcows"}
+		discovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}
+		Expect(ResourceServed(discovery, lolcows)(request)).To(MatchError(ContainSubstring("is the CRD installed?")))
+
+		discovery.Resources = []*metav1.APIResourceList{{
+			GroupVersion: "my.domain/v1alpha1",
+			APIResources: []metav1.APIResource{{Name: "lolcows/status"}},
+		}}
+		Expect(ResourceServed(discovery, lolcows)(request)).To(MatchError(ContainSubstring("lolcows.my.domain is not served")))
+
+		discovery.Resources[0].APIResources = append(discovery.Resources[0].APIResources, metav1.APIResource{Name: "lolcows"})
+		Expect(ResourceServed(discovery, lolcows)(request)).To(Succeed())
+	})
+})
@@ -0,0 +1,147 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Watchdog is the liveness check of a controller. It fails when lolcows are
+// waiting in the workqueue (or being reconciled), and no reconcile finished
+// for Timeout, e.g., because a reconcile hangs. A reconcile that returns an
+// error still counts: the queue is moving, and a restart wouldn't help.
+type Watchdog struct {
+
+	// Name is the controller, which is also the name of its workqueue
+	Name string
+
+	// Timeout is how long pending lolcows may wait without progress
+	Timeout time.Duration
+
+	depth func() (float64, error)
+	now   func() time.Time
+
+	mutex        sync.Mutex
+	reconciling  int
+	lastDone     time.Time
+	pendingSince time.Time
+}
+
+// NewWatchdog watches the workqueue of the named controller
+func NewWatchdog(name string, timeout time.Duration) (*Watchdog, error) {
+	depth, err := queueDepth(metrics.Registry, name)
+	if err != nil {
+		return nil, err
+	}
+	return &Watchdog{Name: name, Timeout: timeout, depth: depth, now: time.Now}, nil
+}
+
+// Wrap tells the watchdog when each reconcile starts and finishes
+func (w *Watchdog) Wrap(r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		w.mutex.Lock()
+		w.reconciling++
+		w.mutex.Unlock()
+		defer func() {
+			w.mutex.Lock()
+			defer w.mutex.Unlock()
+			w.reconciling--
+			w.lastDone = w.now()
+		}()
+		return r.Reconcile(ctx, req)
+	})
+}
+
+// Check is the healthz.Checker. Pending is measured from the first check
+// that saw work waiting, or the last reconcile that finished, if later.
+func (w *Watchdog) Check(_ *http.Request) error {
+	depth, err := w.depth()
+	if err != nil {
+		return err
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	now := w.now()
+	if depth == 0 && w.reconciling == 0 {
+		w.pendingSince = time.Time{}
+		return nil
+	}
+	if w.pendingSince.IsZero() {
+		w.pendingSince = now
+	}
+	since := w.pendingSince
+	if w.lastDone.After(since) {
+		since = w.lastDone
+	}
+	if stuck := now.Sub(since); stuck > w.Timeout {
+		return fmt.Errorf("the %s workqueue is stuck: %d queued and %d reconciling, but no reconcile finished for %s",
+			w.Name, int(depth), w.reconciling, stuck.Round(time.Second))
+	}
+	return nil
+}
+
+// queueDepth reads the workqueue_depth gauge controller-runtime keeps for a
+// queue. The gauge isn't exported, so we register an identical one, and get
+// the existing one back. A queue that wasn't made yet (e.g., when we're not
+// the leader) is empty.
+func queueDepth(registry prometheus.Registerer, name string) (func() (float64, error), error) {
+	depth := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: metrics.WorkQueueSubsystem,
+		Name:      metrics.DepthKey,
+		Help:      "Current depth of workqueue",
+	}, []string{"name"})
+	var registered prometheus.AlreadyRegisteredError
+	if err := registry.Register(depth); errors.As(err, &registered) {
+		depth = registered.ExistingCollector.(*prometheus.GaugeVec)
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot watch the %s workqueue: %w", name, err)
+	}
+
+	return func() (float64, error) {
+		gauges := make(chan prometheus.Metric, 8)
+		go func() {
+			depth.Collect(gauges)
+			close(gauges)
+		}()
+		var found float64
+		var err error
+		for gauge := range gauges {
+			metric := &dto.Metric{}
+			if writeErr := gauge.Write(metric); writeErr != nil {
+				err = writeErr
+				continue
+			}
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "name" && label.GetValue() == name {
+					found = metric.GetGauge().GetValue()
+				}
+			}
+		}
+		return found, err
+	}, nil
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"github.com/go-logr/logr"
+	"go.uber.org/zap/zapcore"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// Log fields, so every line about the same thing uses the same key
+const (
+	KeyLolcow    = "lolcow"
+	KeyNamespace = "namespace"
+	KeyPhase     = "phase"
+	KeyKind      = "kind"
+	KeyChild     = "child"
+
+	// KeyLevel raises the verbosity of a logger when set to LevelDebug
+	KeyLevel   = "logLevel"
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+)
+
+// debugVerbosity is the logr verbosity (V) enabled by LevelDebug
+const debugVerbosity = 1
+
+// New returns a zap logger from the (flag) options, that can be made more
+// verbose for a single object with WithLevel. Unless --zap-devel is set,
+// this is the zap production config: JSON, info level.
+func New(opts *zap.Options) logr.Logger {
+	enabler := opts.Level
+	if enabler == nil {
+		level := zapcore.InfoLevel
+		if opts.Development {
+			level = zapcore.DebugLevel
+		}
+		enabler = level
+	}
+
+	// zap itself lets through debug (or the configured level, if lower),
+	// and we decide what is enabled
+	opts.Level = lowestLevel(enabler)
+	base := zap.New(zap.UseFlagOptions(opts))
+	return base.WithSink(&levelSink{LogSink: base.GetSink(), enabler: enabler})
+}
+
+// WithLevel returns a logger at the given level (debug or info). Anything
+// else leaves the logger as is. This only has an effect on a logger from
+// New (and the ones derived from it); elsewhere it just adds the field.
+func WithLevel(log logr.Logger, level string) logr.Logger {
+	if level != LevelDebug && level != LevelInfo {
+		return log
+	}
+	return log.WithValues(KeyLevel, level)
+}
+
+// lowestLevel is the most verbose zap level enabled, and at least debug
+func lowestLevel(enabler zapcore.LevelEnabler) zapcore.Level {
+	level := zapcore.DebugLevel
+	for level > zapcore.Level(-127) && enabler.Enabled(level-1) {
+		level--
+	}
+	return level
+}
+
+// levelSink filters on the configured level, unless it has been raised to
+// debug with a KeyLevel field. Because it's done with a field, it works
+// through controller-runtime's delegating logger.
+type levelSink struct {
+	logr.LogSink
+	enabler zapcore.LevelEnabler
+	debug   bool
+}
+
+// Enabled is true for levels enabled by the flags, or by a debug field
+// A zap level is the negative of the logr verbosity.
+func (s *levelSink) Enabled(level int) bool {
+	if s.debug && level <= debugVerbosity {
+		return true
+	}
+	return s.enabler.Enabled(zapcore.Level(-level))
+}
+
+// WithValues looks for a KeyLevel field, and passes the values on
+func (s *levelSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	debug := s.debug
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		if key, ok := keysAndValues[i].(string); ok && key == KeyLevel {
+			debug = keysAndValues[i+1] == LevelDebug
+		}
+	}
+	return &levelSink{LogSink: s.LogSink.WithValues(keysAndValues...), enabler: s.enabler, debug: debug}
+}
+
+// WithName passes the name on
+func (s *levelSink) WithName(name string) logr.LogSink {
+	return &levelSink{LogSink: s.LogSink.WithName(name), enabler: s.enabler, debug: s.debug}
+}
+
+// WithCallDepth keeps the caller (file and line) right
+func (s *levelSink) WithCallDepth(depth int) logr.LogSink {
+	sink, ok := s.LogSink.(logr.CallDepthLogSink)
+	if !ok {
+		return s
+	}
+	return &levelSink{LogSink: sink.WithCallDepth(depth), enabler: s.enabler, debug: s.debug}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bytes"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap/zapcore"
+	logctrl "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var _ = Describe("Logging", func() {
+	var out *bytes.Buffer
+
+	BeforeEach(func() {
+		out = &bytes.Buffer{}
+	})
+
+	// newLogger is a logger from New, writing to out
+	newLogger := func(opts zap.Options) logr.Logger {
+		opts.DestWriter = out
+		return New(&opts)
+	}
+
+	// logAll logs at info, and at verbosity 1 and 2
+	logAll := func(log logr.Logger) {
+		log.Info("moo info")
+		log.V(1).Info("moo debug")
+		log.V(2).Info("moo trace")
+	}
+
+	It("logs at info by default", func() {
+		logAll(newLogger(zap.Options{}))
+		Expect(out.String()).To(ContainSubstring("moo info"))
+		Expect(out.String()).NotTo(ContainSubstring("moo debug"))
+	})
+
+	It("logs at debug in development", func() {
+		logAll(newLogger(zap.Options{Development: true}))
+		Expect(out.String()).To(ContainSubstring("moo debug"))
+		Expect(out.String()).NotTo(ContainSubstring("moo trace"))
+	})
+
+	It("logs at the level of the flags, below debug", func() {
+		logAll(newLogger(zap.Options{Level: zapcore.Level(-2)}))
+		Expect(out.String()).To(ContainSubstring("moo trace"))
+	})
+
+	It("keeps errors above the level of the flags", func() {
+		log := newLogger(zap.Options{Level: zapcore.ErrorLevel})
+		log.Info("moo info")
+		log.Error(nil, "moo error")
+		Expect(out.String()).NotTo(ContainSubstring("moo info"))
+		Expect(out.String()).To(ContainSubstring("moo error"))
+	})
+
+	Context("WithLevel", func() {
+		It("turns on debug for one logger only", func() {
+			log := newLogger(zap.Options{})
+			logAll(WithLevel(log, LevelDebug))
+			Expect(out.String()).To(ContainSubstring("moo debug"))
+			Expect(out.String()).To(ContainSubstring(`"logLevel":"debug"`))
+			Expect(out.String()).NotTo(ContainSubstring("moo trace"))
+
+			out.Reset()
+			logAll(log)
+			Expect(out.String()).NotTo(ContainSubstring("moo debug"))
+		})
+
+		It("keeps debug through names and values", func() {
+			log := WithLevel(newLogger(zap.Options{}), LevelDebug).WithName("lolcow").WithValues(KeyLolcow, "hello-world")
+			log.V(1).Info("moo debug")
+			Expect(out.String()).To(ContainSubstring("moo debug"))
+		})
+
+		It("turns debug back off at info", func() {
+			log := WithLevel(WithLevel(newLogger(zap.Options{}), LevelDebug), LevelInfo)
+			logAll(log)
+			Expect(out.String()).To(ContainSubstring("moo info"))
+			Expect(out.String()).NotTo(ContainSubstring("moo debug"))
+		})
+
+		It("doesn't lower the level of the flags", func() {
+			logAll(WithLevel(newLogger(zap.Options{Level: zapcore.Level(-2)}), LevelInfo))
+			Expect(out.String()).To(ContainSubstring("moo trace"))
+		})
+
+		It("ignores other levels", func() {
+			log := newLogger(zap.Options{})
+			Expect(WithLevel(log, "trace")).To(Equal(log))
+			logAll(WithLevel(log, "trace"))
+			Expect(out.String()).NotTo(ContainSubstring("moo debug"))
+			Expect(out.String()).NotTo(ContainSubstring("logLevel"))
+		})
+
+		It("works through the delegating logger", func() {
+			delegating := logctrl.NewDelegatingLogSink(logctrl.NullLogSink{})
+			log := WithLevel(logr.New(delegating).WithName("lolcow"), LevelDebug)
+			delegating.Fulfill(newLogger(zap.Options{}).GetSink())
+			logAll(log)
+			Expect(out.String()).To(ContainSubstring("moo debug"))
+			Expect(out.String()).NotTo(ContainSubstring("moo trace"))
+		})
+	})
+})
@@ -0,0 +1,230 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package manifest reads YAML documents from files, remembering where each
+// one came from, so the commands that read them can say where a problem is.
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"sigs.k8s.io/yaml"
+)
+
+// Stdin is the file name that reads standard input, like kubectl -f -
+const Stdin = "-"
+
+// stdinName is what documents from stdin say they came from
+const stdinName = "<stdin>"
+
+// Document is one object from a file
+type Document struct {
+	File string
+
+	// Line is the first line of the document in the file
+	Line int
+
+	// Raw is the document as YAML (or JSON, for the items of a List)
+	Raw []byte
+
+	TypeMeta metav1.TypeMeta
+}
+
+// String is where the document is, e.g., lolcow.yaml:12
+func (d Document) String() string {
+	return fmt.Sprintf("%s:%d", d.File, d.Line)
+}
+
+// Read reads every document in the files, in order. A List (like kubectl
+// get -o yaml gives you) is read as its items.
+func Read(files []string, stdin io.Reader) ([]Document, error) {
+	documents := []Document{}
+	for _, file := range files {
+		var data []byte
+		var err error
+		name := file
+		if file == Stdin {
+			data, err = io.ReadAll(stdin)
+			name = stdinName
+		} else {
+			data, err = os.ReadFile(file)
+		}
+		if err != nil {
+			return nil, err
+		}
+		found, err := Parse(name, data)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, found...)
+	}
+	return documents, nil
+}
+
+// Parse splits the YAML in data (from file) into its documents
+func Parse(file string, data []byte) ([]Document, error) {
+	documents := []Document{}
+	add := func(line int, raw []byte) error {
+		var typeMeta metav1.TypeMeta
+		if err := yaml.Unmarshal(raw, &typeMeta); err != nil {
+			return fmt.Errorf("%s:%d: %w", file, line, err)
+		}
+		if typeMeta.Kind == "" && len(bytes.TrimSpace(stripComments(raw))) == 0 {
+			return nil
+		}
+		document := Document{File: file, Line: line, Raw: raw, TypeMeta: typeMeta}
+		if typeMeta.Kind != "List" {
+			documents = append(documents, document)
+			return nil
+		}
+		list := struct {
+			Items []json.RawMessage `json:"items"`
+		}{}
+		if err := yaml.Unmarshal(raw, &list); err != nil {
+			return fmt.Errorf("%s: %w", document, err)
+		}
+		for _, item := range list.Items {
+			itemMeta := metav1.TypeMeta{}
+			if err := json.Unmarshal(item, &itemMeta); err != nil {
+				return fmt.Errorf("%s: %w", document, err)
+			}
+			documents = append(documents, Document{File: file, Line: line, Raw: item, TypeMeta: itemMeta})
+		}
+		return nil
+	}
+
+	// Documents are separated by lines starting with ---
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	current := &bytes.Buffer{}
+	start, line := 1, 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if text == "---" || strings.HasPrefix(text, "--- ") {
+			if err := add(start, current.Bytes()); err != nil {
+				return nil, err
+			}
+			current = &bytes.Buffer{}
+			start = line + 1
+			continue
+		}
+		current.WriteString(text)
+		current.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := add(start, current.Bytes()); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// Decode makes the typed object a document holds, with the scheme's kinds
+func Decode(document Document, scheme *runtime.Scheme) (runtime.Object, error) {
+	object, _, err := serializer.NewCodecFactory(scheme).UniversalDeserializer().Decode(document.Raw, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", document, err)
+	}
+	return object, nil
+}
+
+// LineOf is the line in the file of a field, given as a field path like
+// spec.imagePullSecrets[0].name or metadata.labels[app]. If the field isn't
+// there, it's the line of the closest parent that is.
+func (d Document) LineOf(path string) int {
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(d.Raw, &root); err != nil || len(root.Content) == 0 {
+		return d.Line
+	}
+	node := root.Content[0]
+	line := node.Line
+	for _, segment := range splitPath(path) {
+		var next *yamlv3.Node
+		switch node.Kind {
+		case yamlv3.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == segment {
+					next = node.Content[i+1]
+					line = node.Content[i].Line
+					break
+				}
+			}
+		case yamlv3.SequenceNode:
+			if index, err := strconv.Atoi(segment); err == nil && index >= 0 && index < len(node.Content) {
+				next = node.Content[index]
+				line = next.Line
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return d.Line + line - 1
+}
+
+// splitPath splits a field path into its names, keys and indexes
+func splitPath(path string) []string {
+	segments := []string{}
+	current := strings.Builder{}
+	flush := func() {
+		if current.Len() > 0 {
+			segments = append(segments, current.String())
+			current.Reset()
+		}
+	}
+	inBrackets := false
+	for _, c := range path {
+		switch {
+		case c == '[' && !inBrackets:
+			flush()
+			inBrackets = true
+		case c == ']' && inBrackets:
+			flush()
+			inBrackets = false
+		case c == '.' && !inBrackets:
+			flush()
+		default:
+			current.WriteRune(c)
+		}
+	}
+	flush()
+	return segments
+}
+
+// stripComments drops the comment lines, to tell an empty document apart
+func stripComments(raw []byte) []byte {
+	kept := [][]byte{}
+	for _, line := range bytes.Split(raw, []byte("\n")) {
+		if !bytes.HasPrefix(bytes.TrimSpace(line), []byte("#")) {
+			kept = append(kept, line)
+		}
+	}
+	return bytes.Join(kept, []byte("\n"))
+}
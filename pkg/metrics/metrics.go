@@ -0,0 +1,168 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+// These are served by the manager next to the controller-runtime metrics
+// (the same /metrics endpoint config/prometheus/monitor.yaml scrapes).
+const namespace = "lolcow"
+
+var (
+	// GreetingUpdates counts deployments updated with a new greeting
+	GreetingUpdates = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "greeting_updates_total",
+		Help:      "Number of times a lolcow deployment was updated with a new greeting",
+	})
+
+	// PortChanges counts children updated because a port changed
+	PortChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "port_changes_total",
+		Help:      "Number of times a lolcow child was updated with a new port, by kind",
+	}, []string{"kind"})
+
+	// DriftCorrections counts children we put back after someone edited them
+	DriftCorrections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "drift_corrections_total",
+		Help:      "Number of times a lolcow child was edited by hand and put back, by kind",
+	}, []string{"kind"})
+
+	// ChildErrors counts failed operations on children
+	ChildErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "child_errors_total",
+		Help:      "Number of failed get, create, update or delete calls on lolcow children, by kind and operation",
+	}, []string{"kind", "operation"})
+
+	// PhaseDuration times each phase of a reconcile
+	PhaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "reconcile_phase_duration_seconds",
+		Help:      "Time spent in each phase (component) of a lolcow reconcile",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	}, []string{"phase"})
+)
+
+// Register adds the lolcow metrics to the controller-runtime registry
+// The reader (usually the manager's cached client) is used to count
+// lolcows by condition, and to describe each one, when scraped. The default
+// greeting is the one lolcows without their own say.
+func Register(reader client.Reader, defaultGreeting string) {
+	metrics.Registry.MustRegister(
+		GreetingUpdates,
+		PortChanges,
+		DriftCorrections,
+		ChildErrors,
+		PhaseDuration,
+		newLolcowCollector(reader, defaultGreeting),
+	)
+}
+
+// ObservePhase records how long a phase took, from start until now
+func ObservePhase(phase string, start time.Time) {
+	PhaseDuration.WithLabelValues(phase).Observe(time.Since(start).Seconds())
+}
+
+// GreetingHash is a short, stable fingerprint of a greeting
+// We don't put the greeting itself in a label (it can be anything).
+func GreetingHash(greeting string) string {
+	sum := sha256.Sum256([]byte(greeting))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// lolcowCollector reports gauges computed from the Lolcows in the cache
+// at scrape time, so deleted lolcows never leave stale series behind.
+type lolcowCollector struct {
+	reader          client.Reader
+	defaultGreeting string
+	byCondition     *prometheus.Desc
+	info            *prometheus.Desc
+}
+
+func newLolcowCollector(reader client.Reader, defaultGreeting string) *lolcowCollector {
+	return &lolcowCollector{
+		reader:          reader,
+		defaultGreeting: defaultGreeting,
+		byCondition: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "lolcows"),
+			"Number of lolcows by condition type and status",
+			[]string{"condition", "status"}, nil,
+		),
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "info"),
+			"Information about each lolcow, always 1",
+			[]string{"namespace", "name", "greeting_hash"}, nil,
+		),
+	}
+}
+
+// Describe sends the descriptors of the lolcow gauges
+func (c *lolcowCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.byCondition
+	ch <- c.info
+}
+
+// Collect lists the lolcows and reports on them
+func (c *lolcowCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	lolcows := &api.LolcowList{}
+	if err := c.reader.List(ctx, lolcows); err != nil {
+		ch <- prometheus.NewInvalidMetric(c.byCondition, err)
+		return
+	}
+
+	counts := map[[2]string]int{}
+	for _, lolcow := range lolcows.Items {
+		for _, condition := range lolcow.Status.Conditions {
+			counts[[2]string{condition.Type, string(condition.Status)}]++
+		}
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1,
+			lolcow.Namespace, lolcow.Name, GreetingHash(c.greeting(&lolcow)))
+	}
+	for key, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.byCondition, prometheus.GaugeValue, float64(count), key[0], key[1])
+	}
+}
+
+// greeting is what the lolcow's Deployment says: its own greeting, or the
+// default one. We don't read the ConfigMap of greetingFrom, so it's the
+// reference to it, which changes when the lolcow points somewhere else.
+func (c *lolcowCollector) greeting(lolcow *api.Lolcow) string {
+	if from := lolcow.Spec.GreetingFrom; from != nil {
+		return "configMapKeyRef:" + from.Name + "/" + from.Key
+	}
+	if lolcow.Spec.Greeting == "" {
+		return c.defaultGreeting
+	}
+	return lolcow.Spec.Greeting
+}
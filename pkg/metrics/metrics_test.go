@@ -0,0 +1,135 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+// failingReader can't list anything
+type failingReader struct {
+	client.Reader
+}
+
+func (failingReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return errors.New("the cache isn't synced")
+}
+
+var _ = Describe("Metrics", func() {
+
+	// lolcow is a lolcow with a greeting and conditions
+	lolcow := func(namespace, name, greeting string, conditions map[string]metav1.ConditionStatus) *api.Lolcow {
+		l := &api.Lolcow{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec:       api.LolcowSpec{Greeting: greeting},
+		}
+		for condition, status := range conditions {
+			l.Status.Conditions = append(l.Status.Conditions, metav1.Condition{Type: condition, Status: status})
+		}
+		return l
+	}
+
+	// fromConfigMap is a lolcow with its greeting in a ConfigMap key
+	fromConfigMap := func(namespace, name, configMap, key string) *api.Lolcow {
+		l := lolcow(namespace, name, "", nil)
+		l.Spec.GreetingFrom = &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: configMap}, Key: key}
+		return l
+	}
+
+	newReader := func(objects ...client.Object) client.Reader {
+		scheme := runtime.NewScheme()
+		Expect(api.AddToScheme(scheme)).To(Succeed())
+		return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	}
+
+	Context("the lolcow collector", func() {
+		It("counts lolcows by condition, and describes each one", func() {
+			reader := newReader(
+				lolcow("default", "hello-world", "moo", map[string]metav1.ConditionStatus{
+					api.ConditionReady:           metav1.ConditionTrue,
+					api.ConditionDeploymentReady: metav1.ConditionTrue,
+				}),
+				lolcow("cows", "hello-again", "baa", map[string]metav1.ConditionStatus{
+					api.ConditionReady:           metav1.ConditionFalse,
+					api.ConditionDeploymentReady: metav1.ConditionTrue,
+				}),
+				lolcow("cows", "new", "", nil),
+				fromConfigMap("cows", "monday", "greetings", "monday"),
+				fromConfigMap("cows", "tuesday", "greetings", "tuesday"),
+			)
+			expected := `
+# HELP lolcow_info Information about each lolcow, always 1
+# TYPE lolcow_info gauge
+lolcow_info{greeting_hash="` + GreetingHash("baa") + `",name="hello-again",namespace="cows"} 1
+lolcow_info{greeting_hash="` + GreetingHash("configMapKeyRef:greetings/monday") + `",name="monday",namespace="cows"} 1
+lolcow_info{greeting_hash="` + GreetingHash("Moo?") + `",name="new",namespace="cows"} 1
+lolcow_info{greeting_hash="` + GreetingHash("configMapKeyRef:greetings/tuesday") + `",name="tuesday",namespace="cows"} 1
+lolcow_info{greeting_hash="` + GreetingHash("moo") + `",name="hello-world",namespace="default"} 1
+# HELP lolcow_lolcows Number of lolcows by condition type and status
+# TYPE lolcow_lolcows gauge
+lolcow_lolcows{condition="DeploymentReady",status="True"} 2
+lolcow_lolcows{condition="Ready",status="False"} 1
+lolcow_lolcows{condition="Ready",status="True"} 1
+`
+			Expect(testutil.CollectAndCompare(newLolcowCollector(reader, "Moo?"), strings.NewReader(expected))).To(Succeed())
+		})
+
+		It("reports nothing for deleted lolcows", func() {
+			Expect(testutil.CollectAndCount(newLolcowCollector(newReader(), "Moo?"))).To(BeZero())
+		})
+
+		It("fails the scrape when it can't list the lolcows", func() {
+			registry := prometheus.NewPedanticRegistry()
+			Expect(registry.Register(newLolcowCollector(failingReader{}, "Moo?"))).To(Succeed())
+			_, err := registry.Gather()
+			Expect(err).To(MatchError(ContainSubstring("the cache isn't synced")))
+		})
+
+		It("passes the linter", func() {
+			problems, err := testutil.CollectAndLint(newLolcowCollector(newReader(lolcow("default", "hello-world", "moo", nil)), "Moo?"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(problems).To(BeEmpty())
+		})
+	})
+
+	It("hashes greetings short and stable", func() {
+		Expect(GreetingHash("moo")).To(HaveLen(12))
+		Expect(GreetingHash("moo")).To(Equal(GreetingHash("moo")))
+		Expect(GreetingHash("moo")).NotTo(Equal(GreetingHash("baa")))
+	})
+
+	It("times the phases", func() {
+		before := testutil.CollectAndCount(PhaseDuration)
+		ObservePhase("metrics-test", time.Now().Add(-time.Second))
+		Expect(testutil.CollectAndCount(PhaseDuration)).To(Equal(before + 1))
+	})
+})
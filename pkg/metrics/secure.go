@@ -0,0 +1,214 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	logctrl "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"vsoch/lolcow-operator/pkg/certs"
+	"vsoch/lolcow-operator/pkg/rbac"
+)
+
+// Path is where the metrics are served, like the manager does
+const Path = "/metrics"
+
+// SecureServer serves the metrics over HTTPS, to those allowed to read them,
+// instead of the kube-rbac-proxy sidecar. A bearer token is checked with a
+// TokenReview, and its user must be allowed to get /metrics (a
+// SubjectAccessReview), see config/rbac/auth_proxy_client_clusterrole.yaml.
+// Prometheus scrapes rarely enough that every request is reviewed.
+type SecureServer struct {
+
+	// BindAddress is the address to serve on, e.g., :8443
+	BindAddress string
+
+	// CertDir has the serving certificate (tls.crt and tls.key), which is
+	// reloaded when it changes. Without one, a self signed certificate is made.
+	CertDir string
+
+	client kubernetes.Interface
+}
+
+// SecurePermissions are what the secure server needs, the same as
+// config/rbac/auth_proxy_role.yaml
+var SecurePermissions = []rbac.Permission{
+	{Group: "authentication.k8s.io", Resource: "tokenreviews", Verbs: []string{"create"}, NeededBy: "secure metrics"},
+	{Group: "authorization.k8s.io", Resource: "subjectaccessreviews", Verbs: []string{"create"}, NeededBy: "secure metrics"},
+}
+
+// NewSecureServer makes a server that reviews tokens with the API server
+func NewSecureServer(config *rest.Config, bindAddress, certDir string) (*SecureServer, error) {
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &SecureServer{BindAddress: bindAddress, CertDir: certDir, client: client}, nil
+}
+
+// NeedLeaderElection is false, every replica serves its own metrics
+func (s *SecureServer) NeedLeaderElection() bool {
+	return false
+}
+
+// Start serves the metrics until the context is done
+func (s *SecureServer) Start(ctx context.Context) error {
+	log := logctrl.FromContext(ctx).WithName("metrics")
+	tlsConfig, err := s.tlsConfig(ctx)
+	if err != nil {
+		return err
+	}
+	listener, err := tls.Listen("tcp", s.BindAddress, tlsConfig)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(Path, s.authorize(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.HTTPErrorOnError,
+	})))
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 32 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Error(err, "problem stopping the metrics server")
+		}
+	}()
+
+	log.Info("🔒️ Serving metrics over HTTPS", "address", listener.Addr().String(), "path", Path)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// tlsConfig serves the certificate in CertDir, or a self signed one
+func (s *SecureServer) tlsConfig(ctx context.Context) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if s.CertDir == "" {
+		now := time.Now()
+		ca, err := certs.NewCA(now, certs.DefaultValidity)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := certs.NewServingCert(ca, []string{"localhost"}, now, certs.DefaultValidity)
+		if err != nil {
+			return nil, err
+		}
+		pair, err := tls.X509KeyPair(cert.CertPEM, cert.KeyPEM)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{pair}
+		return config, nil
+	}
+
+	watcher, err := certwatcher.New(filepath.Join(s.CertDir, corev1.TLSCertKey), filepath.Join(s.CertDir, corev1.TLSPrivateKeyKey))
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		if err := watcher.Start(ctx); err != nil {
+			logctrl.FromContext(ctx).Error(err, "problem watching the metrics certificate")
+		}
+	}()
+	config.GetCertificate = watcher.GetCertificate
+	return config, nil
+}
+
+// authorize only lets through requests with a bearer token whose user may
+// get the path: 401 without a (valid) token, and 403 if it may not
+func (s *SecureServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log := logctrl.FromContext(r.Context()).WithName("metrics")
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		header := r.Header.Get("Authorization")
+		token := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+		if token == "" || !strings.HasPrefix(header, "Bearer ") {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		review, err := s.client.AuthenticationV1().TokenReviews().Create(r.Context(), &authenticationv1.TokenReview{
+			Spec: authenticationv1.TokenReviewSpec{Token: token},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			log.Error(err, "unable to review a metrics token")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if !review.Status.Authenticated {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		user := review.Status.User
+		extra := map[string]authorizationv1.ExtraValue{}
+		for key, values := range user.Extra {
+			extra[key] = authorizationv1.ExtraValue(values)
+		}
+		access, err := s.client.AuthorizationV1().SubjectAccessReviews().Create(r.Context(), &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				User:   user.Username,
+				UID:    user.UID,
+				Groups: user.Groups,
+				Extra:  extra,
+				NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+					Path: r.URL.Path,
+					Verb: "get",
+				},
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			log.Error(err, "unable to authorize a metrics request", "user", user.Username)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if !access.Status.Allowed {
+			log.V(1).Info("🚫️ Metrics request forbidden", "user", user.Username, "reason", access.Status.Reason)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
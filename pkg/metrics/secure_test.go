@@ -0,0 +1,172 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"vsoch/lolcow-operator/pkg/certs"
+)
+
+var _ = Describe("SecureServer", func() {
+	var (
+		server  *SecureServer
+		reviews []authorizationv1.SubjectAccessReviewSpec
+		allowed bool
+		failing bool
+	)
+
+	BeforeEach(func() {
+		reviews = nil
+		allowed = true
+		failing = false
+
+		// Only "good-token" is valid, for the prometheus service account
+		client := fake.NewSimpleClientset()
+		client.PrependReactor("create", "tokenreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+			review := action.(clienttesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+			if review.Spec.Token == "good-token" {
+				review.Status.Authenticated = true
+				review.Status.User = authenticationv1.UserInfo{
+					Username: "system:serviceaccount:monitoring:prometheus",
+					Groups:   []string{"system:serviceaccounts"},
+				}
+			}
+			return true, review, nil
+		})
+		client.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+			if failing {
+				return true, nil, errors.New("the API server is down")
+			}
+			review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+			reviews = append(reviews, review.Spec)
+			review.Status.Allowed = allowed
+			return true, review, nil
+		})
+		server = &SecureServer{BindAddress: ":0", client: client}
+	})
+
+	// get asks for the metrics with a bearer token (none if empty)
+	get := func(method, token string) *httptest.ResponseRecorder {
+		handler := server.authorize(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("lolcow_lolcows 1\n"))
+		}))
+		request := httptest.NewRequest(method, Path, nil)
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	It("serves the metrics to users allowed to get them", func() {
+		response := get(http.MethodGet, "good-token")
+		Expect(response.Code).To(Equal(http.StatusOK))
+		Expect(response.Body.String()).To(ContainSubstring("lolcow_lolcows"))
+		Expect(reviews).To(ConsistOf(authorizationv1.SubjectAccessReviewSpec{
+			User:                  "system:serviceaccount:monitoring:prometheus",
+			Groups:                []string{"system:serviceaccounts"},
+			Extra:                 map[string]authorizationv1.ExtraValue{},
+			NonResourceAttributes: &authorizationv1.NonResourceAttributes{Path: Path, Verb: "get"},
+		}))
+	})
+
+	It("needs a bearer token", func() {
+		Expect(get(http.MethodGet, "").Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("rejects a token that doesn't authenticate", func() {
+		Expect(get(http.MethodGet, "bad-token").Code).To(Equal(http.StatusUnauthorized))
+		Expect(reviews).To(BeEmpty())
+	})
+
+	It("forbids users that may not get the metrics", func() {
+		allowed = false
+		Expect(get(http.MethodGet, "good-token").Code).To(Equal(http.StatusForbidden))
+	})
+
+	It("fails when it can't review the request", func() {
+		failing = true
+		Expect(get(http.MethodGet, "good-token").Code).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("only serves reads", func() {
+		Expect(get(http.MethodPost, "good-token").Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	Context("certificates", func() {
+		var (
+			ctx    context.Context
+			cancel context.CancelFunc
+		)
+
+		BeforeEach(func() {
+			ctx, cancel = context.WithCancel(context.Background())
+		})
+
+		AfterEach(func() {
+			cancel()
+		})
+
+		It("makes a self signed certificate without a directory", func() {
+			config, err := server.tlsConfig(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Certificates).To(HaveLen(1))
+		})
+
+		It("serves the certificate in the directory", func() {
+			dir, err := os.MkdirTemp("", "metrics")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+			ca, err := certs.NewCA(time.Now(), time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			cert, err := certs.NewServingCert(ca, []string{"metrics.example.com"}, time.Now(), time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(dir, corev1.TLSCertKey), cert.CertPEM, 0o600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, corev1.TLSPrivateKeyKey), cert.KeyPEM, 0o600)).To(Succeed())
+
+			server.CertDir = dir
+			config, err := server.tlsConfig(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			served, err := config.GetCertificate(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(served.Certificate[0]).To(Equal(cert.Cert.Raw))
+		})
+
+		It("fails without the certificate", func() {
+			server.CertDir = "/nonexistent"
+			_, err := server.tlsConfig(ctx)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
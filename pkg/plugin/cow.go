@@ -0,0 +1,89 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// bubbleWidth is where the greeting wraps, like cowsay
+const bubbleWidth = 40
+
+const cow = `        \   ^__^
+         \  (oo)\_______
+            (__)\       )\/\
+                ||----w |
+                ||     ||
+`
+
+// cowsay renders the greeting the way the lolcow container does (minus the colors)
+func cowsay(greeting string) string {
+	lines := wrap(greeting, bubbleWidth)
+	width := 0
+	for _, line := range lines {
+		if n := utf8.RuneCountInString(line); n > width {
+			width = n
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(" " + strings.Repeat("_", width+2) + "\n")
+	for i, line := range lines {
+		left, right := "|", "|"
+		switch {
+		case len(lines) == 1:
+			left, right = "<", ">"
+		case i == 0:
+			left, right = "/", "\\"
+		case i == len(lines)-1:
+			left, right = "\\", "/"
+		}
+		padding := strings.Repeat(" ", width-utf8.RuneCountInString(line))
+		b.WriteString(left + " " + line + padding + " " + right + "\n")
+	}
+	b.WriteString(" " + strings.Repeat("-", width+2) + "\n")
+	b.WriteString(cow)
+	return b.String()
+}
+
+// wrap splits text into lines of at most width runes, at spaces if it can
+func wrap(text string, width int) []string {
+	lines := []string{}
+	line := ""
+	for _, word := range strings.Fields(text) {
+		for utf8.RuneCountInString(word) > width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:width]))
+			word = string(runes[width:])
+		}
+		switch {
+		case line == "":
+			line = word
+		case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	return append(lines, line)
+}
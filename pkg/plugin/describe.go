@@ -0,0 +1,96 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+	"vsoch/lolcow-operator/pkg/sdk"
+)
+
+// newDescribeCommand shows a lolcow in detail, and what it's saying
+func newDescribeCommand(o *Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "describe NAME",
+		Short: "Show a lolcow, its conditions and its greeting",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.describe(cmd.Context(), args[0])
+		},
+	}
+}
+
+func (o *Options) describe(ctx context.Context, name string) error {
+	key := types.NamespacedName{Namespace: o.Namespace, Name: name}
+	lolcow := &api.Lolcow{}
+	if err := o.Client.Get(ctx, key, lolcow); err != nil {
+		return err
+	}
+	if o.Output != OutputTable {
+		return printStructured(o.Out, o.Output, withTypeMeta(lolcow))
+	}
+
+	// The children may not be there (yet), that's fine
+	deployment := &appsv1.Deployment{}
+	if err := o.Client.Get(ctx, key, deployment); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		deployment = nil
+	}
+	service := &corev1.Service{}
+	if err := o.Client.Get(ctx, key, service); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		service = nil
+	}
+
+	w := tabwriter.NewWriter(o.Out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", lolcow.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", lolcow.Namespace)
+	fmt.Fprintf(w, "Greeting:\t%s\n", greetingOf(lolcow, deployment))
+	fmt.Fprintf(w, "Port:\t%d\n", lolcow.Spec.Port)
+	fmt.Fprintf(w, "URL:\t%s\n", serviceURL(service))
+	fmt.Fprintf(w, "Paused:\t%s\n", strconv.FormatBool(sdk.Paused(lolcow)))
+	fmt.Fprintf(w, "Ready:\t%s\n", readyStatus(lolcow))
+	fmt.Fprintf(w, "Age:\t%s\n", age(lolcow.CreationTimestamp))
+	if len(lolcow.Status.Conditions) == 0 {
+		fmt.Fprintf(w, "Conditions:\t%s\n", none)
+	} else {
+		fmt.Fprintln(w, "Conditions:")
+		fmt.Fprintln(w, "  Type\tStatus\tReason\tMessage")
+		for _, condition := range lolcow.Status.Conditions {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", condition.Type, condition.Status, condition.Reason, condition.Message)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(o.Out, "\n%s", cowsay(greetingOf(lolcow, deployment)))
+	return err
+}
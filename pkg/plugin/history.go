@@ -0,0 +1,108 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"vsoch/lolcow-operator/pkg/sdk"
+)
+
+// revisionAnnotation is set by the deployment controller on each ReplicaSet
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// Revision is a greeting the lolcow had, from a ReplicaSet of its Deployment
+type Revision struct {
+	Revision int64       `json:"revision"`
+	Greeting string      `json:"greeting"`
+	Current  bool        `json:"current"`
+	Created  metav1.Time `json:"created"`
+}
+
+// newHistoryCommand lists what a lolcow said, like kubectl rollout history
+func newHistoryCommand(o *Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "history NAME",
+		Short: "List the greetings a lolcow had, oldest first",
+		Long: "List the greetings a lolcow had, oldest first. These come from the ReplicaSets\n" +
+			"of its Deployment, so there are as many as its revisionHistoryLimit keeps.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.history(cmd.Context(), args[0])
+		},
+	}
+}
+
+func (o *Options) history(ctx context.Context, name string) error {
+	revisions, err := o.revisions(ctx, name)
+	if err != nil {
+		return err
+	}
+	if o.Output != OutputTable {
+		return printStructured(o.Out, o.Output, revisions)
+	}
+	rows := [][]string{}
+	for _, revision := range revisions {
+		current := ""
+		if revision.Current {
+			current = "*"
+		}
+		rows = append(rows, []string{strconv.FormatInt(revision.Revision, 10), revision.Greeting, current, age(revision.Created)})
+	}
+	return printTable(o.Out, []string{"REVISION", "GREETING", "CURRENT", "AGE"}, rows)
+}
+
+// revisions are the ReplicaSets of the lolcow's Deployment, oldest first
+func (o *Options) revisions(ctx context.Context, name string) ([]Revision, error) {
+	deployment := &appsv1.Deployment{}
+	if err := o.Client.Get(ctx, types.NamespacedName{Namespace: o.Namespace, Name: name}, deployment); err != nil {
+		return nil, err
+	}
+	replicaSets := &appsv1.ReplicaSetList{}
+	if err := o.Client.List(ctx, replicaSets, client.InNamespace(o.Namespace)); err != nil {
+		return nil, err
+	}
+
+	current := deployment.Annotations[revisionAnnotation]
+	revisions := []Revision{}
+	for i := range replicaSets.Items {
+		rs := &replicaSets.Items[i]
+		if !metav1.IsControlledBy(rs, deployment) {
+			continue
+		}
+		number, err := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+		if err != nil {
+			continue
+		}
+		revisions = append(revisions, Revision{
+			Revision: number,
+			Greeting: sdk.TemplateGreeting(&rs.Spec.Template),
+			Current:  rs.Annotations[revisionAnnotation] == current,
+			Created:  rs.CreationTimestamp,
+		})
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision < revisions[j].Revision })
+	return revisions, nil
+}
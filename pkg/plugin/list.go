@@ -0,0 +1,105 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+// newListCommand lists the lolcows, with their conditions and where to find them
+func newListCommand(o *Options) *cobra.Command {
+	allNamespaces := false
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the lolcows, their conditions and URLs",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.list(cmd.Context(), allNamespaces)
+		},
+	}
+	cmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "List the lolcows in every namespace.")
+	return cmd
+}
+
+func (o *Options) list(ctx context.Context, allNamespaces bool) error {
+	namespace := []client.ListOption{client.InNamespace(o.Namespace)}
+	if allNamespaces {
+		namespace = nil
+	}
+
+	lolcows := &api.LolcowList{}
+	if err := o.Client.List(ctx, lolcows, namespace...); err != nil {
+		return err
+	}
+	if o.Output != OutputTable {
+		lolcows.APIVersion = api.GroupVersion.String()
+		lolcows.Kind = "LolcowList"
+		for i := range lolcows.Items {
+			withTypeMeta(&lolcows.Items[i])
+		}
+		return printStructured(o.Out, o.Output, lolcows)
+	}
+
+	// Each lolcow has a Deployment and Service named after it
+	services := &corev1.ServiceList{}
+	if err := o.Client.List(ctx, services, namespace...); err != nil {
+		return err
+	}
+	deployments := &appsv1.DeploymentList{}
+	if err := o.Client.List(ctx, deployments, namespace...); err != nil {
+		return err
+	}
+	serviceByName := map[types.NamespacedName]*corev1.Service{}
+	for i, service := range services.Items {
+		serviceByName[client.ObjectKeyFromObject(&service)] = &services.Items[i]
+	}
+	deploymentByName := map[types.NamespacedName]*appsv1.Deployment{}
+	for i, deployment := range deployments.Items {
+		deploymentByName[client.ObjectKeyFromObject(&deployment)] = &deployments.Items[i]
+	}
+
+	header := []string{"NAME", "GREETING", "READY", "CONDITIONS", "URL"}
+	if allNamespaces {
+		header = append([]string{"NAMESPACE"}, header...)
+	}
+	rows := [][]string{}
+	for i := range lolcows.Items {
+		lolcow := &lolcows.Items[i]
+		key := client.ObjectKeyFromObject(lolcow)
+		row := []string{
+			lolcow.Name,
+			greetingOf(lolcow, deploymentByName[key]),
+			readyStatus(lolcow),
+			conditionSummary(lolcow),
+			serviceURL(serviceByName[key]),
+		}
+		if allNamespaces {
+			row = append([]string{lolcow.Namespace}, row...)
+		}
+		rows = append(rows, row)
+	}
+	return printTable(o.Out, header, rows)
+}
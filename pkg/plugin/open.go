@@ -0,0 +1,82 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"vsoch/lolcow-operator/pkg/sdk"
+)
+
+// newOpenCommand port-forwards to a lolcow, so you can see it in the browser
+func newOpenCommand(o *Options) *cobra.Command {
+	address := "localhost"
+	localPort := 0
+	cmd := &cobra.Command{
+		Use:   "open NAME",
+		Short: "Port-forward to a lolcow, and print its URL",
+		Long: "Port-forward to a pod behind the lolcow's Service, and print the URL to open.\n" +
+			"It keeps forwarding until you stop it with Ctrl+C.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.open(cmd.Context(), args[0], address, localPort)
+		},
+	}
+	cmd.Flags().StringVar(&address, "address", address, "The local address to listen on.")
+	cmd.Flags().IntVar(&localPort, "port", localPort, "The local port to listen on, 0 picks a free one.")
+	return cmd
+}
+
+func (o *Options) open(ctx context.Context, name, address string, localPort int) error {
+	service := &corev1.Service{}
+	if err := o.Client.Get(ctx, types.NamespacedName{Namespace: o.Namespace, Name: name}, service); err != nil {
+		return err
+	}
+	pod, podPort, err := sdk.ServicePod(ctx, o.Client, service)
+	if err != nil {
+		return err
+	}
+	forward, err := sdk.StartPortForward(ctx, o.RESTConfig, pod, address, localPort, podPort, o.ErrOut)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	defer forward.Close()
+
+	if o.Output != OutputTable {
+		err = printStructured(o.Out, o.Output, map[string]string{"url": forward.URL})
+	} else {
+		_, err = fmt.Fprintf(o.Out, "🐮 lolcow/%s is at %s (Ctrl+C to stop)\n", name, forward.URL)
+	}
+	if err != nil {
+		return err
+	}
+
+	select {
+	case err := <-forward.Done():
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package plugin is kubectl-lolcow, the kubectl plugin for lolcows
+// Install the binary on your PATH and run kubectl lolcow --help.
+package plugin
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+// Output formats, for -o
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(api.AddToScheme(scheme))
+}
+
+// Options are shared by every command
+type Options struct {
+	Kubeconfig string
+	Context    string
+	Namespace  string
+	Output     string
+
+	Out    io.Writer
+	ErrOut io.Writer
+
+	// Client and RESTConfig come from the kubeconfig, unless they are set
+	// already (the tests set them)
+	Client     client.Client
+	RESTConfig *rest.Config
+}
+
+// NewCommand is kubectl lolcow, with all of its subcommands
+func NewCommand(o *Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "kubectl-lolcow",
+		Short:         "Talk to your lolcows 🐮",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return o.Complete()
+		},
+	}
+	cmd.SetOut(o.Out)
+	cmd.SetErr(o.ErrOut)
+
+	flags := cmd.PersistentFlags()
+	flags.StringVar(&o.Kubeconfig, "kubeconfig", "", "Path to the kubeconfig file, defaults to KUBECONFIG and then ~/.kube/config.")
+	flags.StringVar(&o.Context, "context", "", "The kubeconfig context to use.")
+	flags.StringVarP(&o.Namespace, "namespace", "n", "", "The namespace of the lolcows, defaults to the one of the context.")
+	flags.StringVarP(&o.Output, "output", "o", OutputTable, "Output format: table, json or yaml.")
+
+	cmd.AddCommand(
+		newSayCommand(o),
+		newListCommand(o),
+		newDescribeCommand(o),
+		newOpenCommand(o),
+		newHistoryCommand(o),
+	)
+	return cmd
+}
+
+// Complete checks the output format and connects to the cluster
+func (o *Options) Complete() error {
+	switch o.Output {
+	case OutputTable, OutputJSON, OutputYAML:
+	default:
+		return fmt.Errorf("unknown output format %q, use table, json or yaml", o.Output)
+	}
+	if o.Client != nil {
+		if o.Namespace == "" {
+			o.Namespace = "default"
+		}
+		return nil
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = o.Kubeconfig
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules,
+		&clientcmd.ConfigOverrides{CurrentContext: o.Context})
+	config, err := loader.ClientConfig()
+	if err != nil {
+		return err
+	}
+	if o.Namespace == "" {
+		if o.Namespace, _, err = loader.Namespace(); err != nil {
+			return err
+		}
+	}
+	o.RESTConfig = config
+	o.Client, err = client.New(config, client.Options{Scheme: scheme})
+	return err
+}
@@ -0,0 +1,287 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+var _ = Describe("kubectl lolcow", func() {
+	var (
+		ctx       context.Context
+		namespace string
+		lolcow    *api.Lolcow
+		count     int
+	)
+
+	// run runs kubectl lolcow in the test namespace, returning what it printed
+	run := func(args ...string) (string, error) {
+		out := &bytes.Buffer{}
+		cmd := NewCommand(&Options{Client: k8sClient, RESTConfig: cfg, Out: out, ErrOut: GinkgoWriter})
+		cmd.SetArgs(append(args, "--namespace", namespace))
+		err := cmd.ExecuteContext(ctx)
+		return out.String(), err
+	}
+
+	// setReady reports the lolcow ready at its generation, like the operator would
+	setReady := func() {
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(lolcow), lolcow)).To(Succeed())
+		lolcow.Status.ObservedGeneration = lolcow.Generation
+		for _, condition := range []string{api.ConditionDeploymentReady, api.ConditionServiceReady, api.ConditionReady} {
+			meta.SetStatusCondition(&lolcow.Status.Conditions, metav1.Condition{
+				Type:               condition,
+				Status:             metav1.ConditionTrue,
+				ObservedGeneration: lolcow.Generation,
+				Reason:             "Ready",
+				Message:            condition + " is true",
+			})
+		}
+		Expect(k8sClient.Status().Update(ctx, lolcow)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pollInterval = 100 * time.Millisecond
+		count++
+		namespace = fmt.Sprintf("plugin-%d", count)
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+
+		lolcow = &api.Lolcow{
+			ObjectMeta: metav1.ObjectMeta{Name: "hello-world", Namespace: namespace},
+			Spec:       api.LolcowSpec{Port: 30080, ServiceType: corev1.ServiceTypeNodePort, Greeting: "Moo"},
+		}
+		Expect(k8sClient.Create(ctx, lolcow)).To(Succeed())
+	})
+
+	It("rejects an unknown output format", func() {
+		_, err := run("list", "-o", "xml")
+		Expect(err).To(MatchError(ContainSubstring(`unknown output format "xml"`)))
+	})
+
+	Context("list", func() {
+		BeforeEach(func() {
+			setReady()
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: lolcow.Name, Namespace: namespace},
+				Spec: corev1.ServiceSpec{
+					Type:  corev1.ServiceTypeClusterIP,
+					Ports: []corev1.ServicePort{{Name: "lolcow", Port: 80, TargetPort: intstr.FromString("lolcow")}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, service)).To(Succeed())
+		})
+
+		It("shows the greeting, conditions and URL", func() {
+			out, err := run("list")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(ContainSubstring("NAME"))
+			Expect(out).To(ContainSubstring("hello-world"))
+			Expect(out).To(ContainSubstring("Moo"))
+			Expect(out).To(ContainSubstring("DeploymentReady=True,ServiceReady=True"))
+			Expect(out).To(ContainSubstring(fmt.Sprintf("http://hello-world.%s.svc:80", namespace)))
+		})
+
+		It("prints json", func() {
+			out, err := run("list", "-o", "json")
+			Expect(err).NotTo(HaveOccurred())
+			lolcows := &api.LolcowList{}
+			Expect(json.Unmarshal([]byte(out), lolcows)).To(Succeed())
+			Expect(lolcows.Kind).To(Equal("LolcowList"))
+			Expect(lolcows.Items).To(HaveLen(1))
+			Expect(lolcows.Items[0].Kind).To(Equal("Lolcow"))
+			Expect(lolcows.Items[0].Spec.Greeting).To(Equal("Moo"))
+		})
+
+		It("prints yaml", func() {
+			out, err := run("list", "-o", "yaml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(ContainSubstring("kind: LolcowList"))
+			Expect(out).To(ContainSubstring("greeting: Moo"))
+		})
+	})
+
+	Context("describe", func() {
+		It("draws the lolcow saying its greeting", func() {
+			setReady()
+			out, err := run("describe", "hello-world")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(ContainSubstring("Greeting:"))
+			Expect(out).To(ContainSubstring("DeploymentReady"))
+			Expect(out).To(ContainSubstring("< Moo >"))
+			Expect(out).To(ContainSubstring("(oo)"))
+		})
+
+		It("fails for a lolcow that isn't there", func() {
+			_, err := run("describe", "nope")
+			Expect(err).To(MatchError(ContainSubstring("not found")))
+		})
+	})
+
+	Context("say", func() {
+		It("sets the greeting without waiting", func() {
+			out, err := run("say", "hello-world", "Moo", "to", "you", "--wait=false")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(ContainSubstring("says: Moo to you"))
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(lolcow), lolcow)).To(Succeed())
+			Expect(lolcow.Spec.Greeting).To(Equal("Moo to you"))
+		})
+
+		It("waits for the new greeting to roll out", func() {
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+
+				// Pretend to be the operator, once the greeting changed
+				Eventually(func() string {
+					Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(lolcow), lolcow)).To(Succeed())
+					return lolcow.Spec.Greeting
+				}, 10*time.Second, 100*time.Millisecond).Should(Equal("Hello"))
+				setReady()
+			}()
+			out, err := run("say", "hello-world", "Hello", "-o", "json")
+			Expect(err).NotTo(HaveOccurred())
+			<-done
+
+			said := &api.Lolcow{}
+			Expect(json.Unmarshal([]byte(out), said)).To(Succeed())
+			Expect(said.Spec.Greeting).To(Equal("Hello"))
+			Expect(said.Status.ObservedGeneration).To(Equal(said.Generation))
+		})
+
+		It("gives up after the timeout", func() {
+			_, err := run("say", "hello-world", "Hello", "--timeout", "1s")
+			Expect(err).To(MatchError(ContainSubstring("did not roll out")))
+		})
+
+		It("doesn't wait for a paused lolcow", func() {
+			lolcow.Annotations = map[string]string{api.PausedAnnotation: "true"}
+			Expect(k8sClient.Update(ctx, lolcow)).To(Succeed())
+			out, err := run("say", "hello-world", "Hello", "--timeout", "1s")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(ContainSubstring("says: Hello"))
+		})
+	})
+
+	Context("history", func() {
+		var deployment *appsv1.Deployment
+
+		template := func(greeting string) corev1.PodTemplateSpec {
+			return corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "hello-world"}},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{
+					Name:    "hello-world",
+					Image:   "ghcr.io/vsoch/lolcow-operator",
+					Command: []string{"/bin/bash", "/entrypoint.sh", greeting},
+				}}},
+			}
+		}
+
+		BeforeEach(func() {
+			deployment = &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "hello-world",
+					Namespace:   namespace,
+					Annotations: map[string]string{revisionAnnotation: "2"},
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "hello-world"}},
+					Template: template("Moo again"),
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).To(Succeed())
+
+			// The deployment controller would make these
+			for revision, greeting := range map[string]string{"1": "Moo", "2": "Moo again"} {
+				rs := &appsv1.ReplicaSet{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "hello-world-" + revision,
+						Namespace:   namespace,
+						Annotations: map[string]string{revisionAnnotation: revision},
+					},
+					Spec: appsv1.ReplicaSetSpec{
+						Selector: deployment.Spec.Selector,
+						Template: template(greeting),
+					},
+				}
+				isController := true
+				rs.OwnerReferences = []metav1.OwnerReference{{
+					APIVersion: "apps/v1",
+					Kind:       "Deployment",
+					Name:       deployment.Name,
+					UID:        deployment.UID,
+					Controller: &isController,
+				}}
+				Expect(k8sClient.Create(ctx, rs)).To(Succeed())
+			}
+		})
+
+		It("lists the greetings, oldest first", func() {
+			out, err := run("history", "hello-world")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(MatchRegexp(`(?s)REVISION.*1\s+Moo\s+.*2\s+Moo again\s+\*`))
+		})
+
+		It("prints json", func() {
+			out, err := run("history", "hello-world", "-o", "json")
+			Expect(err).NotTo(HaveOccurred())
+			revisions := []Revision{}
+			Expect(json.Unmarshal([]byte(out), &revisions)).To(Succeed())
+			Expect(revisions).To(HaveLen(2))
+			Expect(revisions[0].Greeting).To(Equal("Moo"))
+			Expect(revisions[0].Current).To(BeFalse())
+			Expect(revisions[1].Greeting).To(Equal("Moo again"))
+			Expect(revisions[1].Current).To(BeTrue())
+		})
+
+		It("uses the deployment's greeting in list", func() {
+			out, err := run("list")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(ContainSubstring("Moo again"))
+		})
+	})
+
+	Context("open", func() {
+		It("fails without running pods", func() {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "hello-world", Namespace: namespace},
+				Spec: corev1.ServiceSpec{
+					Selector: map[string]string{"app": "hello-world"},
+					Ports:    []corev1.ServicePort{{Name: "lolcow", Port: 80, TargetPort: intstr.FromString("lolcow")}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, service)).To(Succeed())
+			_, err := run("open", "hello-world")
+			Expect(err).To(MatchError(ContainSubstring("no running pods")))
+		})
+	})
+})
@@ -0,0 +1,141 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/yaml"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+	"vsoch/lolcow-operator/pkg/sdk"
+)
+
+const none = "<none>"
+
+// printStructured writes object as json or yaml
+func printStructured(out io.Writer, format string, object interface{}) error {
+	switch format {
+	case OutputJSON:
+		content, err := json.MarshalIndent(object, "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(content))
+		return err
+	case OutputYAML:
+		content, err := yaml.Marshal(object)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(content)
+		return err
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// printTable writes tab separated rows as aligned columns
+func printTable(out io.Writer, header []string, rows [][]string) error {
+	w := tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// withTypeMeta fills in the kind the client leaves out, for json and yaml
+func withTypeMeta(lolcow *api.Lolcow) *api.Lolcow {
+	lolcow.APIVersion = api.GroupVersion.String()
+	lolcow.Kind = "Lolcow"
+	return lolcow
+}
+
+// age is how long ago, like kubectl get prints it
+func age(t metav1.Time) string {
+	if t.IsZero() {
+		return none
+	}
+	return duration.HumanDuration(time.Since(t.Time))
+}
+
+// readyStatus is the status of the Ready condition, or Unknown
+func readyStatus(lolcow *api.Lolcow) string {
+	if condition := meta.FindStatusCondition(lolcow.Status.Conditions, api.ConditionReady); condition != nil {
+		return string(condition.Status)
+	}
+	return string(metav1.ConditionUnknown)
+}
+
+// conditionSummary lists the conditions other than Ready, e.g., DeploymentReady=True
+func conditionSummary(lolcow *api.Lolcow) string {
+	summary := []string{}
+	for _, condition := range lolcow.Status.Conditions {
+		if condition.Type != api.ConditionReady {
+			summary = append(summary, condition.Type+"="+string(condition.Status))
+		}
+	}
+	if len(summary) == 0 {
+		return none
+	}
+	return strings.Join(summary, ",")
+}
+
+// greetingOf is what the lolcow says: the greeting of its Deployment, if
+// it has one (this includes the operator's default), or else its spec
+func greetingOf(lolcow *api.Lolcow, deployment *appsv1.Deployment) string {
+	if deployment != nil {
+		if greeting := sdk.TemplateGreeting(&deployment.Spec.Template); greeting != "" {
+			return greeting
+		}
+	}
+	return lolcow.Spec.Greeting
+}
+
+// serviceURL is where to reach the lolcow. A load balancer is reachable
+// from outside, a node port on any node, and a cluster IP from a pod.
+func serviceURL(service *corev1.Service) string {
+	if service == nil || len(service.Spec.Ports) == 0 {
+		return none
+	}
+	port := service.Spec.Ports[0]
+	if service.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			host := ingress.IP
+			if host == "" {
+				host = ingress.Hostname
+			}
+			if host != "" {
+				return fmt.Sprintf("http://%s:%d", host, port.Port)
+			}
+		}
+	}
+	if port.NodePort != 0 {
+		return fmt.Sprintf("http://<node-ip>:%d", port.NodePort)
+	}
+	return fmt.Sprintf("http://%s.%s.svc:%d", service.Name, service.Namespace, port.Port)
+}
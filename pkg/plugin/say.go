@@ -0,0 +1,94 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+	"vsoch/lolcow-operator/pkg/sdk"
+)
+
+// pollInterval is how often say checks on the rollout
+var pollInterval = time.Second
+
+// newSayCommand sets the greeting of a lolcow, and waits for it to roll out
+func newSayCommand(o *Options) *cobra.Command {
+	waitForRollout := true
+	timeout := 2 * time.Minute
+	cmd := &cobra.Command{
+		Use:     "say NAME GREETING...",
+		Short:   "Give a lolcow a new greeting, and wait for it to roll out",
+		Example: "  kubectl lolcow say hello-world Moo, I am a cow",
+		Args:    cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			greeting := strings.Join(args[1:], " ")
+			return o.say(cmd.Context(), args[0], greeting, waitForRollout, timeout)
+		},
+	}
+	cmd.Flags().BoolVar(&waitForRollout, "wait", waitForRollout, "Wait for the lolcow to be ready with the new greeting.")
+	cmd.Flags().DurationVar(&timeout, "timeout", timeout, "How long to wait for the rollout.")
+	return cmd
+}
+
+func (o *Options) say(ctx context.Context, name, greeting string, waitForRollout bool, timeout time.Duration) error {
+	key := types.NamespacedName{Namespace: o.Namespace, Name: name}
+	lolcow := &api.Lolcow{}
+	if err := o.Client.Get(ctx, key, lolcow); err != nil {
+		return err
+	}
+	patch := client.MergeFrom(lolcow.DeepCopy())
+	lolcow.Spec.Greeting = greeting
+	if err := o.Client.Patch(ctx, lolcow, patch); err != nil {
+		return err
+	}
+
+	// The operator won't touch a paused lolcow, so there is nothing to wait for
+	if sdk.Paused(lolcow) && waitForRollout {
+		fmt.Fprintf(o.ErrOut, "⏸️ lolcow/%s is paused, it will say this when it's resumed\n", name)
+		waitForRollout = false
+	}
+	if waitForRollout {
+		fmt.Fprintf(o.ErrOut, "⏳ Waiting for lolcow/%s to roll out...\n", name)
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		generation := lolcow.Generation
+		err := wait.PollImmediateUntilWithContext(ctx, pollInterval, func(ctx context.Context) (bool, error) {
+			if err := o.Client.Get(ctx, key, lolcow); err != nil {
+				return false, err
+			}
+			return sdk.ReadyAt(lolcow, generation), nil
+		})
+		if err != nil {
+			return fmt.Errorf("lolcow/%s did not roll out: %w", name, err)
+		}
+	}
+
+	if o.Output != OutputTable {
+		return printStructured(o.Out, o.Output, withTypeMeta(lolcow))
+	}
+	_, err := fmt.Fprintf(o.Out, "🐮 lolcow/%s says: %s\n", name, greeting)
+	return err
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	authorizationclient "k8s.io/client-go/kubernetes/typed/authorization/v1"
+)
+
+// Permission is something the manager needs to do to the API server. The
+// manager checks it has them at startup, with SelfSubjectAccessReviews, so a
+// wrong role shows up then, and not when a Create fails later on.
+type Permission struct {
+
+	// Group, Resource and Subresource, e.g., "apps" and "deployments"
+	Group       string
+	Resource    string
+	Subresource string
+
+	// Verbs needed, e.g., get, list and watch
+	Verbs []string
+
+	// Namespace is where it's needed, "" for every namespace (or cluster scoped)
+	Namespace string
+
+	// Name limits it to one object
+	Name string
+
+	// NeededBy is what needs it, e.g., "lolcow controller"
+	NeededBy string
+
+	// FeatureGate needs it, and can be turned off when it's missing
+	FeatureGate string
+}
+
+// Missing is a verb the manager isn't allowed
+type Missing struct {
+	Permission
+	Verb   string
+	Reason string
+}
+
+// Check asks the API server about every verb of every permission, and
+// returns the ones that are missing
+func Check(ctx context.Context, client authorizationclient.SelfSubjectAccessReviewInterface, permissions []Permission) ([]Missing, error) {
+	missing := []Missing{}
+	for _, permission := range permissions {
+		for _, verb := range permission.Verbs {
+			review, err := client.Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Namespace:   permission.Namespace,
+						Verb:        verb,
+						Group:       permission.Group,
+						Resource:    permission.Resource,
+						Subresource: permission.Subresource,
+						Name:        permission.Name,
+					},
+				},
+			}, metav1.CreateOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("cannot check %s on %s: %w", verb, permission.resource(), err)
+			}
+			if !review.Status.Allowed {
+				missing = append(missing, Missing{Permission: permission, Verb: verb, Reason: review.Status.Reason})
+			}
+		}
+	}
+	return missing, nil
+}
+
+// PrintTable writes the missing permissions as a table, like kubectl would
+func PrintTable(out io.Writer, missing []Missing) error {
+	w := tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(w, "VERB\tGROUP\tRESOURCE\tNAMESPACE\tNAME\tNEEDED BY")
+	for _, m := range missing {
+		group := m.Group
+		if group == "" {
+			group = "core"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			m.Verb, group, m.resource(), orAll(m.Namespace), orAll(m.Name), m.NeededBy)
+	}
+	return w.Flush()
+}
+
+// FeatureGates are the feature gates that miss a permission, sorted
+func FeatureGates(missing []Missing) []string {
+	gates := map[string]bool{}
+	for _, m := range missing {
+		if m.FeatureGate != "" {
+			gates[m.FeatureGate] = true
+		}
+	}
+	names := []string{}
+	for name := range gates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Required are the missing permissions no feature gate can do without
+func Required(missing []Missing) []Missing {
+	required := []Missing{}
+	for _, m := range missing {
+		if m.FeatureGate == "" {
+			required = append(required, m)
+		}
+	}
+	return required
+}
+
+// LeaderElection is what controller-runtime's leader election needs, for a
+// resource lock (e.g., leases) in the namespace
+func LeaderElection(namespace, lock string) []Permission {
+	permissions := []Permission{}
+	verbs := []string{"get", "create", "update"}
+	if lock == "" || strings.Contains(lock, "leases") {
+		permissions = append(permissions, Permission{
+			Group: "coordination.k8s.io", Resource: "leases", Verbs: verbs, Namespace: namespace, NeededBy: "leader election",
+		})
+	}
+	if strings.Contains(lock, "configmaps") {
+		permissions = append(permissions, Permission{
+			Resource: "configmaps", Verbs: verbs, Namespace: namespace, NeededBy: "leader election",
+		})
+	}
+	return permissions
+}
+
+// resource is the resource and subresource, e.g., lolcows/status
+func (p Permission) resource() string {
+	if p.Subresource == "" {
+		return p.Resource
+	}
+	return p.Resource + "/" + p.Subresource
+}
+
+func orAll(value string) string {
+	if value == "" {
+		return "*"
+	}
+	return value
+}
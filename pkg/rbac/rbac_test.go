@@ -0,0 +1,153 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+var _ = Describe("Permissions", func() {
+	var (
+		client  *fake.Clientset
+		reviews []authorizationv1.ResourceAttributes
+		failing bool
+	)
+
+	// Only the networkpolicies and secret writes are denied
+	denied := func(attributes *authorizationv1.ResourceAttributes) bool {
+		return attributes.Resource == "networkpolicies" ||
+			(attributes.Resource == "secrets" && attributes.Verb != "get")
+	}
+
+	BeforeEach(func() {
+		reviews = nil
+		failing = false
+		client = fake.NewSimpleClientset()
+		client.PrependReactor("create", "selfsubjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+			if failing {
+				return true, nil, errors.New("the API server is down")
+			}
+			review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+			attributes := review.Spec.ResourceAttributes
+			reviews = append(reviews, *attributes)
+			if denied(attributes) {
+				review.Status.Reason = "no RBAC policy matched"
+			} else {
+				review.Status.Allowed = true
+			}
+			return true, review, nil
+		})
+	})
+
+	permissions := []Permission{
+		{Group: "apps", Resource: "deployments", Verbs: []string{"get", "list"}, NeededBy: "lolcow controller"},
+		{Group: "networking.k8s.io", Resource: "networkpolicies", Verbs: []string{"get", "create"},
+			Namespace: "cows", NeededBy: "lolcow controller", FeatureGate: "NetworkPolicy"},
+		{Resource: "secrets", Verbs: []string{"get", "update"}, Namespace: "system",
+			Name: "webhook-certs", NeededBy: "webhook certificates"},
+	}
+
+	It("reviews every verb, and returns the denied ones", func() {
+		missing, err := Check(context.Background(), client.AuthorizationV1().SelfSubjectAccessReviews(), permissions)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reviews).To(HaveLen(6))
+		Expect(reviews[0]).To(Equal(authorizationv1.ResourceAttributes{Verb: "get", Group: "apps", Resource: "deployments"}))
+		Expect(reviews[5]).To(Equal(authorizationv1.ResourceAttributes{
+			Namespace: "system", Verb: "update", Resource: "secrets", Name: "webhook-certs",
+		}))
+
+		Expect(missing).To(HaveLen(3))
+		Expect(missing[0].Verb).To(Equal("get"))
+		Expect(missing[0].Permission).To(Equal(permissions[1]))
+		Expect(missing[0].Reason).To(Equal("no RBAC policy matched"))
+		Expect(missing[1].Verb).To(Equal("create"))
+		Expect(missing[2].Verb).To(Equal("update"))
+		Expect(missing[2].Permission).To(Equal(permissions[2]))
+	})
+
+	It("sends subresources", func() {
+		_, err := Check(context.Background(), client.AuthorizationV1().SelfSubjectAccessReviews(), []Permission{
+			{Group: "my.domain", Resource: "lolcows", Subresource: "status", Verbs: []string{"patch"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reviews).To(ConsistOf(authorizationv1.ResourceAttributes{
+			Verb: "patch", Group: "my.domain", Resource: "lolcows", Subresource: "status",
+		}))
+	})
+
+	It("says which permission it couldn't check", func() {
+		failing = true
+		_, err := Check(context.Background(), client.AuthorizationV1().SelfSubjectAccessReviews(), permissions)
+		Expect(err).To(MatchError(ContainSubstring("cannot check get on deployments")))
+	})
+
+	It("prints a table of the missing permissions", func() {
+		missing, err := Check(context.Background(), client.AuthorizationV1().SelfSubjectAccessReviews(), permissions)
+		Expect(err).NotTo(HaveOccurred())
+
+		out := &bytes.Buffer{}
+		Expect(PrintTable(out, missing)).To(Succeed())
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		Expect(lines).To(HaveLen(4))
+		Expect(strings.Fields(lines[0])).To(Equal([]string{"VERB", "GROUP", "RESOURCE", "NAMESPACE", "NAME", "NEEDED", "BY"}))
+		Expect(strings.Fields(lines[1])).To(Equal([]string{"get", "networking.k8s.io", "networkpolicies", "cows", "*", "lolcow", "controller"}))
+		Expect(strings.Fields(lines[3])).To(Equal([]string{"update", "core", "secrets", "system", "webhook-certs", "webhook", "certificates"}))
+
+		// The columns line up
+		Expect(strings.Index(lines[1], "networkpolicies")).To(Equal(strings.Index(lines[0], "RESOURCE")))
+	})
+
+	It("splits the missing permissions by feature gate", func() {
+		missing := []Missing{
+			{Permission: Permission{Resource: "secrets"}, Verb: "get"},
+			{Permission: Permission{Resource: "networkpolicies", FeatureGate: "NetworkPolicy"}, Verb: "get"},
+			{Permission: Permission{Resource: "networkpolicies", FeatureGate: "NetworkPolicy"}, Verb: "create"},
+			{Permission: Permission{Resource: "widgets", FeatureGate: "Alpha"}, Verb: "get"},
+		}
+		Expect(FeatureGates(missing)).To(Equal([]string{"Alpha", "NetworkPolicy"}))
+		Expect(Required(missing)).To(Equal(missing[:1]))
+
+		Expect(FeatureGates(nil)).To(BeEmpty())
+		Expect(Required(nil)).To(BeEmpty())
+	})
+
+	It("knows what each leader election lock needs", func() {
+		resources := func(permissions []Permission) []string {
+			names := []string{}
+			for _, permission := range permissions {
+				Expect(permission.Namespace).To(Equal("system"))
+				Expect(permission.Verbs).To(Equal([]string{"get", "create", "update"}))
+				names = append(names, permission.Group+"/"+permission.Resource)
+			}
+			return names
+		}
+		Expect(resources(LeaderElection("system", ""))).To(Equal([]string{"coordination.k8s.io/leases"}))
+		Expect(resources(LeaderElection("system", "leases"))).To(Equal([]string{"coordination.k8s.io/leases"}))
+		Expect(resources(LeaderElection("system", "configmaps"))).To(Equal([]string{"/configmaps"}))
+		Expect(resources(LeaderElection("system", "configmapsleases"))).To(Equal([]string{"coordination.k8s.io/leases", "/configmaps"}))
+	})
+})
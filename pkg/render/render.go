@@ -0,0 +1,270 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package render is lolcow-operator render, which prints the objects the
+// operator would make for some lolcows, without a cluster. It runs the
+// same components as the controller, so the two can't disagree.
+package render
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	configv1alpha1 "vsoch/lolcow-operator/api/config/v1alpha1"
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+	controllers "vsoch/lolcow-operator/controllers/lolcow"
+	"vsoch/lolcow-operator/pkg/config"
+	"vsoch/lolcow-operator/pkg/manifest"
+	"vsoch/lolcow-operator/pkg/sdk"
+)
+
+// ErrDifferent is returned by --diff when applying the lolcows would change something
+var ErrDifferent = errors.New("the lolcows would change their resources")
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(api.AddToScheme(scheme))
+}
+
+// Options for a render
+type Options struct {
+	// Files with lolcows, - is stdin. Other kinds are skipped.
+	Files []string
+
+	// Namespace of lolcows that don't have one
+	Namespace string
+
+	// Diff prints what would change, instead of the objects
+	Diff bool
+
+	// State is a file with the resources as they are (e.g., from kubectl
+	// get -o yaml). When it's empty, --diff compares with the cluster.
+	State string
+
+	// Kubeconfig for the cluster, if not the default
+	Kubeconfig string
+
+	// Config is the operator configuration (default image, greeting...)
+	Config *configv1alpha1.OperatorConfig
+
+	In     io.Reader
+	Out    io.Writer
+	ErrOut io.Writer
+
+	// Reader of the resources as they are, from State or the cluster if unset
+	Reader client.Reader
+}
+
+// Run parses the render flags in args, and renders
+func Run(ctx context.Context, args []string, in io.Reader, out, errOut io.Writer) error {
+	o := &Options{In: in, Out: out, ErrOut: errOut}
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	fs.Usage = func() {
+		fmt.Fprintln(errOut, "Usage: lolcow-operator render -f lolcow.yaml [--diff [--state resources.yaml]] [flags]")
+		fmt.Fprintln(errOut, "\nPrint the resources the operator would make for the lolcows, without a cluster.")
+		fmt.Fprintln(errOut, "The operator flags (and --config) change what's rendered, like they change the operator.")
+		fmt.Fprint(errOut, "With --diff, it exits 1 if anything would change.\n\n")
+		fs.PrintDefaults()
+	}
+	files := fileList{}
+	fs.Var(&files, "f", "A file with lolcows, - for stdin. Can be given more than once.")
+	fs.Var(&files, "filename", "The same as -f.")
+	fs.StringVar(&o.Namespace, "namespace", "default", "The namespace of lolcows that don't have one.")
+	fs.BoolVar(&o.Diff, "diff", false, "Print what would change, against the cluster or --state.")
+	fs.StringVar(&o.State, "state", "", "A file with the resources as they are (kubectl get -o yaml), instead of the cluster.")
+	fs.StringVar(&o.Kubeconfig, "kubeconfig", "", "The kubeconfig for --diff, defaults to KUBECONFIG and then ~/.kube/config.")
+	configFlags := config.BindFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	o.Files = files
+	if len(o.Files) == 0 {
+		fs.Usage()
+		return fmt.Errorf("render needs at least one -f")
+	}
+
+	var err error
+	o.Config, err = configFlags.Load()
+	if err == nil {
+		err = config.Validate(o.Config)
+	}
+	if err != nil {
+		return err
+	}
+	return o.Render(ctx)
+}
+
+// Render prints the resources of every lolcow in the files, or with Diff,
+// what would change. It returns ErrDifferent if Diff found changes.
+func (o *Options) Render(ctx context.Context) error {
+	lolcows, err := o.lolcows()
+	if err != nil {
+		return err
+	}
+	reader, err := o.reader()
+	if err != nil {
+		return err
+	}
+	reconciler := controllers.NewLolcowReconciler(nil, scheme, controllers.ConfigOptions(o.Config)...)
+
+	changed := false
+	printed := 0
+	for _, lolcow := range lolcows {
+		if sdk.Paused(lolcow) {
+			fmt.Fprintf(o.ErrOut, "⏸️ lolcow %s/%s is paused, the operator leaves its resources alone\n", lolcow.Namespace, lolcow.Name)
+		}
+		rendered, err := reconciler.Render(ctx, lolcow, reader)
+		if err != nil {
+			return fmt.Errorf("rendering lolcow %s/%s: %w", lolcow.Namespace, lolcow.Name, err)
+		}
+		for _, object := range rendered {
+			if o.Diff {
+				changed = printChange(o.Out, object) || changed
+				continue
+			}
+			if printed > 0 {
+				fmt.Fprintln(o.Out, "---")
+			}
+			raw, err := yaml.Marshal(object.Object)
+			if err != nil {
+				return err
+			}
+			if _, err := o.Out.Write(raw); err != nil {
+				return err
+			}
+			printed++
+		}
+	}
+	if changed {
+		return ErrDifferent
+	}
+	return nil
+}
+
+// printChange prints what would happen to a resource, if anything
+func printChange(out io.Writer, rendered controllers.Rendered) bool {
+	symbol := map[string]string{
+		controllers.ActionCreated: "+",
+		controllers.ActionUpdated: "~",
+		controllers.ActionDeleted: "-",
+	}[rendered.Action]
+	if symbol == "" {
+		return false
+	}
+	object := rendered.Object
+	fmt.Fprintf(out, "%s %s %s/%s\n", symbol, object.GetObjectKind().GroupVersionKind().Kind, object.GetNamespace(), object.GetName())
+	for _, change := range rendered.Changes {
+		fmt.Fprintf(out, "    %s\n", change)
+	}
+	return true
+}
+
+// lolcows reads the lolcows from the files
+func (o *Options) lolcows() ([]*api.Lolcow, error) {
+	documents, err := manifest.Read(o.Files, o.In)
+	if err != nil {
+		return nil, err
+	}
+	lolcows := []*api.Lolcow{}
+	for _, document := range documents {
+		if document.TypeMeta.Kind != "Lolcow" {
+			continue
+		}
+		object, err := manifest.Decode(document, scheme)
+		if err != nil {
+			return nil, err
+		}
+		lolcow := object.(*api.Lolcow)
+		if lolcow.Namespace == "" {
+			lolcow.Namespace = o.Namespace
+		}
+		lolcows = append(lolcows, lolcow)
+	}
+	if len(lolcows) == 0 {
+		return nil, fmt.Errorf("no lolcows in %s", strings.Join(o.Files, ", "))
+	}
+	return lolcows, nil
+}
+
+// reader is where the resources are read from: the saved state, the
+// cluster for a diff, or nowhere, so everything is created
+func (o *Options) reader() (client.Reader, error) {
+	if o.Reader != nil {
+		return o.Reader, nil
+	}
+	if o.State == "" && o.Diff {
+		var restConfig *rest.Config
+		var err error
+		if o.Kubeconfig != "" {
+			restConfig, err = clientcmd.BuildConfigFromFlags("", o.Kubeconfig)
+		} else {
+			restConfig, err = ctrl.GetConfig()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return client.New(restConfig, client.Options{Scheme: scheme})
+	}
+
+	// A saved state is served by controller-runtime's fake client, so
+	// the components read it just like the cluster
+	objects := []client.Object{}
+	if o.State != "" {
+		documents, err := manifest.Read([]string{o.State}, o.In)
+		if err != nil {
+			return nil, err
+		}
+		for _, document := range documents {
+			object, err := manifest.Decode(document, scheme)
+			if err != nil {
+				return nil, err
+			}
+			if object, ok := object.(client.Object); ok {
+				objects = append(objects, object)
+			}
+		}
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(), nil
+}
+
+// fileList is a flag that can be given more than once
+type fileList []string
+
+func (f *fileList) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *fileList) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
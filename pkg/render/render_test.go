@@ -0,0 +1,160 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/yaml"
+
+	"vsoch/lolcow-operator/pkg/config"
+	"vsoch/lolcow-operator/pkg/manifest"
+)
+
+const lolcow = `apiVersion: my.domain/v1alpha1
+kind: Lolcow
+metadata:
+  name: hello-world
+spec:
+  port: 30080
+  greeting: Moo
+  serviceType: NodePort
+`
+
+var _ = Describe("lolcow-operator render", func() {
+	var (
+		dir    string
+		out    *bytes.Buffer
+		errOut *bytes.Buffer
+	)
+
+	// write puts content in a file in dir, and returns its path
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+		return path
+	}
+
+	run := func(args ...string) error {
+		out.Reset()
+		errOut.Reset()
+		return Run(context.Background(), args, strings.NewReader(""), out, errOut)
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "render")
+		Expect(err).NotTo(HaveOccurred())
+		out = &bytes.Buffer{}
+		errOut = &bytes.Buffer{}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("prints the resources of a lolcow", func() {
+		Expect(run("-f", write("lolcow.yaml", lolcow))).To(Succeed())
+		documents, err := manifest.Parse("out", out.Bytes())
+		Expect(err).NotTo(HaveOccurred())
+		kinds := []string{}
+		for _, document := range documents {
+			kinds = append(kinds, document.TypeMeta.Kind)
+		}
+		Expect(kinds).To(Equal([]string{"ServiceAccount", "Deployment", "Service"}))
+
+		deployment := &appsv1.Deployment{}
+		Expect(yaml.Unmarshal(documents[1].Raw, deployment)).To(Succeed())
+		Expect(deployment.Namespace).To(Equal("default"))
+		container := deployment.Spec.Template.Spec.Containers[0]
+		Expect(container.Command).To(Equal([]string{"/bin/bash", "/entrypoint.sh", "Moo"}))
+		Expect(container.Image).To(Equal(config.DefaultImage))
+		Expect(deployment.OwnerReferences[0].Name).To(Equal("hello-world"))
+
+		service := &corev1.Service{}
+		Expect(yaml.Unmarshal(documents[2].Raw, service)).To(Succeed())
+		Expect(service.Spec.Type).To(Equal(corev1.ServiceTypeNodePort))
+		Expect(service.Spec.Ports[0].NodePort).To(Equal(int32(30080)))
+	})
+
+	It("uses the operator flags", func() {
+		withPolicy := lolcow + "  networkPolicy: {}\n"
+		Expect(run("-f", write("lolcow.yaml", withPolicy), "--default-image", "example.com/cow:v2", "--namespace", "cows")).To(Succeed())
+		documents, err := manifest.Parse("out", out.Bytes())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(documents).To(HaveLen(4))
+
+		deployment := &appsv1.Deployment{}
+		Expect(yaml.Unmarshal(documents[1].Raw, deployment)).To(Succeed())
+		Expect(deployment.Namespace).To(Equal("cows"))
+		Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("example.com/cow:v2"))
+		policy := &networkingv1.NetworkPolicy{}
+		Expect(yaml.Unmarshal(documents[3].Raw, policy)).To(Succeed())
+		Expect(policy.Kind).To(Equal("NetworkPolicy"))
+	})
+
+	It("skips other kinds, and needs a lolcow", func() {
+		mixed := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: other\n---\n" + lolcow
+		Expect(run("-f", write("mixed.yaml", mixed))).To(Succeed())
+		Expect(out.String()).NotTo(ContainSubstring("ConfigMap"))
+
+		err := run("-f", write("empty.yaml", "# nothing here\n"))
+		Expect(err).To(MatchError(ContainSubstring("no lolcows")))
+	})
+
+	Context("--diff", func() {
+		var state string
+
+		BeforeEach(func() {
+			Expect(run("-f", write("lolcow.yaml", lolcow))).To(Succeed())
+			state = write("state.yaml", out.String())
+		})
+
+		It("finds nothing to change for the same lolcow", func() {
+			Expect(run("-f", filepath.Join(dir, "lolcow.yaml"), "--diff", "--state", state)).To(Succeed())
+			Expect(out.String()).To(BeEmpty())
+		})
+
+		It("prints the changes the operator would make", func() {
+			changed := strings.Replace(lolcow, "greeting: Moo", "greeting: Baa", 1)
+			err := run("-f", write("changed.yaml", changed), "--diff", "--state", state)
+			Expect(err).To(MatchError(ErrDifferent))
+			Expect(out.String()).To(Equal("~ Deployment default/hello-world\n" +
+				"    spec.template.spec.containers[0].command[2]: \"Moo\" -> \"Baa\"\n"))
+		})
+
+		It("prints what would be created and deleted", func() {
+			withPolicy := lolcow + "  networkPolicy: {}\n"
+			Expect(run("-f", write("policy.yaml", withPolicy), "--diff", "--state", state)).To(MatchError(ErrDifferent))
+			Expect(out.String()).To(Equal("+ NetworkPolicy default/hello-world\n"))
+
+			Expect(run("-f", filepath.Join(dir, "policy.yaml"))).To(Succeed())
+			withPolicyState := write("policy-state.yaml", out.String())
+			Expect(run("-f", filepath.Join(dir, "lolcow.yaml"), "--diff", "--state", withPolicyState)).To(MatchError(ErrDifferent))
+			Expect(out.String()).To(Equal("- NetworkPolicy default/hello-world\n"))
+		})
+	})
+})
@@ -0,0 +1,142 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sdk helps Go programs (and tests) make lolcows, wait for them
+// and hear what they say.
+package sdk
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+// LolcowBuilder builds a Lolcow one field at a time
+//
+//	lolcow := sdk.NewLolcow("hello-world", "default").
+//		WithGreeting("Moo").
+//		WithPort(30080).
+//		Build()
+type LolcowBuilder struct {
+	lolcow api.Lolcow
+}
+
+// NewLolcow starts a Lolcow with a name and namespace
+func NewLolcow(name, namespace string) *LolcowBuilder {
+	return &LolcowBuilder{lolcow: api.Lolcow{
+		TypeMeta:   metav1.TypeMeta{APIVersion: api.GroupVersion.String(), Kind: "Lolcow"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+	}}
+}
+
+// WithGreeting sets what the lolcow says
+func (b *LolcowBuilder) WithGreeting(greeting string) *LolcowBuilder {
+	b.lolcow.Spec.Greeting = greeting
+	return b
+}
+
+// WithGreetingFrom reads what the lolcow says from a ConfigMap key, instead of a greeting
+func (b *LolcowBuilder) WithGreetingFrom(configMap, key string) *LolcowBuilder {
+	b.lolcow.Spec.GreetingFrom = &corev1.ConfigMapKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: configMap},
+		Key:                  key,
+	}
+	return b
+}
+
+// WithServiceType sets how the lolcow is exposed, instead of the operator's default
+func (b *LolcowBuilder) WithServiceType(serviceType corev1.ServiceType) *LolcowBuilder {
+	b.lolcow.Spec.ServiceType = serviceType
+	return b
+}
+
+// WithPort sets the lolcow port, the node port for a NodePort service
+func (b *LolcowBuilder) WithPort(port int32) *LolcowBuilder {
+	b.lolcow.Spec.Port = port
+	return b
+}
+
+// WithContainerPort sets the port the lolcow web server listens on
+func (b *LolcowBuilder) WithContainerPort(port int32) *LolcowBuilder {
+	b.lolcow.Spec.ContainerPort = port
+	return b
+}
+
+// WithServicePort sets the port the lolcow Service exposes
+func (b *LolcowBuilder) WithServicePort(port int32) *LolcowBuilder {
+	b.lolcow.Spec.ServicePort = port
+	return b
+}
+
+// WithImage sets the image serving the greeting, instead of the operator's default
+func (b *LolcowBuilder) WithImage(image string) *LolcowBuilder {
+	b.lolcow.Spec.Image = image
+	return b
+}
+
+// WithLabels adds labels, which the operator propagates to the children
+func (b *LolcowBuilder) WithLabels(labels map[string]string) *LolcowBuilder {
+	if b.lolcow.Labels == nil {
+		b.lolcow.Labels = map[string]string{}
+	}
+	for key, value := range labels {
+		b.lolcow.Labels[key] = value
+	}
+	return b
+}
+
+// WithAnnotations adds annotations, which the operator propagates to the children
+func (b *LolcowBuilder) WithAnnotations(annotations map[string]string) *LolcowBuilder {
+	if b.lolcow.Annotations == nil {
+		b.lolcow.Annotations = map[string]string{}
+	}
+	for key, value := range annotations {
+		b.lolcow.Annotations[key] = value
+	}
+	return b
+}
+
+// WithServiceAccountName runs the lolcow pods as an existing ServiceAccount
+func (b *LolcowBuilder) WithServiceAccountName(name string) *LolcowBuilder {
+	b.lolcow.Spec.ServiceAccountName = name
+	return b
+}
+
+// WithImagePullSecrets adds secrets to pull the lolcow image with
+func (b *LolcowBuilder) WithImagePullSecrets(names ...string) *LolcowBuilder {
+	for _, name := range names {
+		b.lolcow.Spec.ImagePullSecrets = append(b.lolcow.Spec.ImagePullSecrets, corev1.LocalObjectReference{Name: name})
+	}
+	return b
+}
+
+// WithNetworkPolicy restricts ingress to the given peers, none denies all
+func (b *LolcowBuilder) WithNetworkPolicy(allowedFrom ...api.LolcowNetworkPeer) *LolcowBuilder {
+	b.lolcow.Spec.NetworkPolicy = &api.LolcowNetworkPolicy{AllowedFrom: allowedFrom}
+	return b
+}
+
+// Suspended sets spec.suspend, so the operator leaves the lolcow alone
+func (b *LolcowBuilder) Suspended(suspend bool) *LolcowBuilder {
+	b.lolcow.Spec.Suspend = &suspend
+	return b
+}
+
+// Build returns the Lolcow. The builder can keep going, and build another.
+func (b *LolcowBuilder) Build() *api.Lolcow {
+	return b.lolcow.DeepCopy()
+}
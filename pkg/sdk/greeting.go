@@ -0,0 +1,237 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// wisdom is where the lolcow page shows the greeting
+var wisdom = regexp.MustCompile(`(?s)<div class="wisdom">(.*?)</div>`)
+
+// ParseGreeting finds the greeting in a lolcow page
+func ParseGreeting(page []byte) (string, error) {
+	match := wisdom.FindSubmatch(page)
+	if match == nil {
+		return "", fmt.Errorf("no greeting in the lolcow page")
+	}
+	return strings.TrimSpace(html.UnescapeString(string(match[1]))), nil
+}
+
+// GetGreeting asks the lolcow what it says, through the API server's
+// service proxy. This needs get on services/proxy, but no open ports.
+func GetGreeting(ctx context.Context, config *rest.Config, key types.NamespacedName) (string, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return "", err
+	}
+	service, err := clientset.CoreV1().Services(key.Namespace).Get(ctx, key.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if len(service.Spec.Ports) == 0 {
+		return "", fmt.Errorf("service %s has no ports", key)
+	}
+	port := strconv.Itoa(int(service.Spec.Ports[0].Port))
+	page, err := clientset.CoreV1().Services(key.Namespace).ProxyGet("http", key.Name, port, "/", nil).DoRaw(ctx)
+	if err != nil {
+		return "", fmt.Errorf("proxying to service %s: %w", key, err)
+	}
+	return ParseGreeting(page)
+}
+
+// GetGreetingPortForward asks the lolcow what it says, through a
+// port-forward to one of its pods, like kubectl port-forward
+func GetGreetingPortForward(ctx context.Context, c client.Client, config *rest.Config, key types.NamespacedName) (string, error) {
+	service := &corev1.Service{}
+	if err := c.Get(ctx, key, service); err != nil {
+		return "", err
+	}
+	pod, podPort, err := ServicePod(ctx, c, service)
+	if err != nil {
+		return "", err
+	}
+	forward, err := StartPortForward(ctx, config, pod, "localhost", 0, podPort, io.Discard)
+	if err != nil {
+		return "", err
+	}
+	defer forward.Close()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, forward.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("lolcow %s answered %s", key, response.Status)
+	}
+	page, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+	return ParseGreeting(page)
+}
+
+// PortForward forwards a local port to a pod, until it's closed or its
+// context is done
+type PortForward struct {
+	// URL is the local address of the forwarded port
+	URL string
+
+	stop  chan struct{}
+	done  chan error
+	close sync.Once
+}
+
+// Close stops forwarding, it's safe to call more than once
+func (f *PortForward) Close() {
+	f.close.Do(func() { close(f.stop) })
+}
+
+// Done gets the error (or nil) when forwarding stops
+func (f *PortForward) Done() <-chan error {
+	return f.done
+}
+
+// StartPortForward forwards address:localPort (0 picks a free port) to the
+// pod port, and returns once it's listening. Forwarding errors go to errOut.
+func StartPortForward(ctx context.Context, config *rest.Config, pod *corev1.Pod, address string, localPort int, podPort int32, errOut io.Writer) (*PortForward, error) {
+	if config == nil {
+		return nil, fmt.Errorf("port-forwarding needs a connection to the cluster")
+	}
+
+	// This is what kubectl port-forward does
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return nil, err
+	}
+	url := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(pod.Namespace).Name(pod.Name).SubResource("portforward").URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	forward := &PortForward{stop: make(chan struct{}), done: make(chan error, 1)}
+	ready := make(chan struct{})
+	ports := []string{fmt.Sprintf("%d:%d", localPort, podPort)}
+	forwarder, err := portforward.NewOnAddresses(dialer, []string{address}, ports, forward.stop, ready, io.Discard, errOut)
+	if err != nil {
+		return nil, err
+	}
+	go func() { forward.done <- forwarder.ForwardPorts() }()
+
+	select {
+	case err := <-forward.done:
+		if err == nil {
+			err = fmt.Errorf("port-forward to pod %s stopped", pod.Name)
+		}
+		return nil, err
+	case <-ctx.Done():
+		forward.Close()
+		return nil, ctx.Err()
+	case <-ready:
+	}
+	forwarded, err := forwarder.GetPorts()
+	if err != nil {
+		forward.Close()
+		return nil, err
+	}
+	forward.URL = fmt.Sprintf("http://%s:%d", address, forwarded[0].Local)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			forward.Close()
+		case <-forward.stop:
+		}
+	}()
+	return forward, nil
+}
+
+// ServicePod picks a running pod behind the service, preferring a ready
+// one, and the pod port the service sends traffic to
+func ServicePod(ctx context.Context, c client.Client, service *corev1.Service) (*corev1.Pod, int32, error) {
+	if len(service.Spec.Selector) == 0 || len(service.Spec.Ports) == 0 {
+		return nil, 0, fmt.Errorf("service %s has no pods to forward to", service.Name)
+	}
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(service.Namespace), client.MatchingLabels(service.Spec.Selector)); err != nil {
+		return nil, 0, err
+	}
+
+	var pod *corev1.Pod
+	for i := range pods.Items {
+		candidate := &pods.Items[i]
+		if candidate.Status.Phase != corev1.PodRunning || !candidate.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if pod == nil || (podReady(candidate) && !podReady(pod)) {
+			pod = candidate
+		}
+	}
+	if pod == nil {
+		return nil, 0, fmt.Errorf("no running pods for service %s", service.Name)
+	}
+
+	// The lolcow service targets the container port by name
+	target := service.Spec.Ports[0].TargetPort
+	if target.Type == intstr.Int {
+		return pod, target.IntVal, nil
+	}
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.Name == target.StrVal {
+				return pod, port.ContainerPort, nil
+			}
+		}
+	}
+	return nil, 0, fmt.Errorf("pod %s has no port named %s", pod.Name, target.StrVal)
+}
+
+// podReady is true when the pod's Ready condition is
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+var _ = Describe("SDK", func() {
+	var (
+		ctx       context.Context
+		namespace string
+		key       types.NamespacedName
+		count     int
+	)
+
+	// markReady does the operator's part, for the lolcow's current generation
+	markReady := func(lolcow *api.Lolcow) {
+		lolcow.Status.ObservedGeneration = lolcow.Generation
+		meta.SetStatusCondition(&lolcow.Status.Conditions, metav1.Condition{
+			Type:               api.ConditionReady,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: lolcow.Generation,
+			Reason:             "Ready",
+			Message:            "All components are ready",
+		})
+		Expect(k8sClient.Status().Update(ctx, lolcow)).To(Succeed())
+	}
+
+	// deployment is what the operator would make, saying greeting
+	deployment := func(greeting string) *appsv1.Deployment {
+		labels := map[string]string{"app": key.Name}
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{
+						Name:    "lolcow",
+						Image:   "ghcr.io/vsoch/lolcow-operator:latest",
+						Command: []string{"/bin/bash", "/entrypoint.sh", greeting},
+					}}},
+				},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		PollInterval = 100 * time.Millisecond
+		count++
+		namespace = fmt.Sprintf("sdk-%d", count)
+		key = types.NamespacedName{Namespace: namespace, Name: "hello-world"}
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+	})
+
+	Context("Building a lolcow", func() {
+		It("sets every field", func() {
+			lolcow := NewLolcow("hello-world", namespace).
+				WithGreeting("Moo").
+				WithPort(30080).
+				WithContainerPort(9090).
+				WithServicePort(8080).
+				WithImage("ghcr.io/vsoch/lolcow-operator:v1").
+				WithLabels(map[string]string{"team": "cows"}).
+				WithAnnotations(map[string]string{"note": "moo"}).
+				WithServiceAccountName("cow").
+				WithImagePullSecrets("registry").
+				WithNetworkPolicy(api.LolcowNetworkPeer{CIDR: "10.0.0.0/16"}).
+				Suspended(true).
+				Build()
+			Expect(k8sClient.Create(ctx, lolcow)).To(Succeed())
+
+			created := &api.Lolcow{}
+			Expect(k8sClient.Get(ctx, key, created)).To(Succeed())
+			Expect(created.Spec.Greeting).To(Equal("Moo"))
+			Expect(created.Spec.Port).To(Equal(int32(30080)))
+			Expect(created.Spec.ContainerPort).To(Equal(int32(9090)))
+			Expect(created.Spec.ServicePort).To(Equal(int32(8080)))
+			Expect(created.Spec.Image).To(Equal("ghcr.io/vsoch/lolcow-operator:v1"))
+			Expect(created.Labels).To(HaveKeyWithValue("team", "cows"))
+			Expect(created.Annotations).To(HaveKeyWithValue("note", "moo"))
+			Expect(created.Spec.ServiceAccountName).To(Equal("cow"))
+			Expect(created.Spec.ImagePullSecrets).To(ConsistOf(corev1.LocalObjectReference{Name: "registry"}))
+			Expect(created.Spec.NetworkPolicy.AllowedFrom).To(HaveLen(1))
+			Expect(Paused(created)).To(BeTrue())
+		})
+
+		It("builds copies", func() {
+			builder := NewLolcow("hello-world", namespace).WithGreeting("Moo")
+			first := builder.Build()
+			second := builder.WithGreeting("Baa").Build()
+			Expect(first.Spec.Greeting).To(Equal("Moo"))
+			Expect(second.Spec.Greeting).To(Equal("Baa"))
+		})
+	})
+
+	Context("Waiting for a lolcow", func() {
+		var lolcow *api.Lolcow
+
+		BeforeEach(func() {
+			lolcow = NewLolcow("hello-world", namespace).WithGreeting("Moo").WithPort(30080).Build()
+			Expect(k8sClient.Create(ctx, lolcow)).To(Succeed())
+		})
+
+		It("waits until it is ready", func() {
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(300 * time.Millisecond)
+				markReady(lolcow.DeepCopy())
+			}()
+			ready, err := WaitForReady(ctx, k8sClient, key, 10*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(Ready(ready)).To(BeTrue())
+		})
+
+		It("times out when it is not ready", func() {
+			_, err := WaitForReady(ctx, k8sClient, key, 500*time.Millisecond)
+			Expect(err).To(MatchError(ContainSubstring("is not ready")))
+		})
+
+		It("is not ready for an old generation", func() {
+			markReady(lolcow)
+			lolcow.Spec.Greeting = "Baa"
+			Expect(k8sClient.Update(ctx, lolcow)).To(Succeed())
+			Expect(Ready(lolcow)).To(BeFalse())
+			Expect(ReadyAt(lolcow, lolcow.Generation-1)).To(BeTrue())
+		})
+
+		It("waits for the greeting to roll out", func() {
+			markReady(lolcow)
+			Expect(k8sClient.Create(ctx, deployment("Baa"))).To(Succeed())
+
+			_, err := WaitForGreeting(ctx, k8sClient, key, "Moo", 500*time.Millisecond)
+			Expect(err).To(MatchError(ContainSubstring(`does not say "Moo"`)))
+
+			moo := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, key, moo)).To(Succeed())
+			moo.Spec.Template.Spec.Containers[0].Command[2] = "Moo"
+			Expect(k8sClient.Update(ctx, moo)).To(Succeed())
+			_, err = WaitForGreeting(ctx, k8sClient, key, "Moo", 10*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("Reading the greeting", func() {
+		It("parses the lolcow page", func() {
+			page := []byte("<body>\n  <div class=\"wisdom\">Moo &amp; &#34;hello&#34;</div>\n</body>")
+			greeting, err := ParseGreeting(page)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(greeting).To(Equal(`Moo & "hello"`))
+
+			_, err = ParseGreeting([]byte("<body></body>"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("picks a ready pod behind the service", func() {
+			labels := map[string]string{"app": "hello-world"}
+			for _, name := range []string{"starting", "ready"} {
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{
+						Name:  "lolcow",
+						Image: "ghcr.io/vsoch/lolcow-operator:latest",
+						Ports: []corev1.ContainerPort{{Name: "lolcow", ContainerPort: 8080}},
+					}}},
+				}
+				Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+				ready := corev1.ConditionFalse
+				if name == "ready" {
+					ready = corev1.ConditionTrue
+				}
+				pod.Status.Phase = corev1.PodRunning
+				pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}}
+				Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+			}
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "hello-world", Namespace: namespace},
+				Spec: corev1.ServiceSpec{
+					Selector: labels,
+					Ports:    []corev1.ServicePort{{Name: "lolcow", Port: 80, TargetPort: intstr.FromString("lolcow")}},
+				},
+			}
+			pod, port, err := ServicePod(ctx, k8sClient, service)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Name).To(Equal("ready"))
+			Expect(port).To(Equal(int32(8080)))
+		})
+	})
+})
@@ -0,0 +1,116 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+)
+
+// PollInterval is how often the wait helpers check on a lolcow
+var PollInterval = time.Second
+
+// Ready is true when the operator saw the lolcow's generation, and all is ready
+func Ready(lolcow *api.Lolcow) bool {
+	return ReadyAt(lolcow, lolcow.Generation)
+}
+
+// ReadyAt is true when the operator saw generation, and all is ready
+func ReadyAt(lolcow *api.Lolcow, generation int64) bool {
+	if lolcow.Status.ObservedGeneration < generation {
+		return false
+	}
+	ready := meta.FindStatusCondition(lolcow.Status.Conditions, api.ConditionReady)
+	return ready != nil && ready.ObservedGeneration >= generation && ready.Status == metav1.ConditionTrue
+}
+
+// Paused is true for a suspended lolcow, or one with the paused annotation
+func Paused(lolcow *api.Lolcow) bool {
+	if lolcow.Spec.Suspend != nil && *lolcow.Spec.Suspend {
+		return true
+	}
+	paused, err := strconv.ParseBool(lolcow.Annotations[api.PausedAnnotation])
+	return err == nil && paused
+}
+
+// WaitForReady waits up to timeout for the lolcow to be Ready, and returns it
+func WaitForReady(ctx context.Context, c client.Client, key types.NamespacedName, timeout time.Duration) (*api.Lolcow, error) {
+	lolcow := &api.Lolcow{}
+	err := poll(ctx, timeout, func(ctx context.Context) (bool, error) {
+		if err := c.Get(ctx, key, lolcow); err != nil {
+			return false, err
+		}
+		return Ready(lolcow), nil
+	})
+	if err != nil {
+		return lolcow, fmt.Errorf("lolcow %s is not ready: %w", key, err)
+	}
+	return lolcow, nil
+}
+
+// WaitForGreeting waits up to timeout for the lolcow to be Ready, with its
+// Deployment rolled out to greeting, and returns it
+func WaitForGreeting(ctx context.Context, c client.Client, key types.NamespacedName, greeting string, timeout time.Duration) (*api.Lolcow, error) {
+	lolcow := &api.Lolcow{}
+	err := poll(ctx, timeout, func(ctx context.Context) (bool, error) {
+		if err := c.Get(ctx, key, lolcow); err != nil {
+			return false, err
+		}
+		if !Ready(lolcow) {
+			return false, nil
+		}
+		deployment := &appsv1.Deployment{}
+		if err := c.Get(ctx, key, deployment); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		return TemplateGreeting(&deployment.Spec.Template) == greeting, nil
+	})
+	if err != nil {
+		return lolcow, fmt.Errorf("lolcow %s does not say %q: %w", key, greeting, err)
+	}
+	return lolcow, nil
+}
+
+// TemplateGreeting is the greeting in a lolcow pod template (of its
+// Deployment or a ReplicaSet), the last argument of its command
+func TemplateGreeting(template *corev1.PodTemplateSpec) string {
+	for _, container := range template.Spec.Containers {
+		if len(container.Command) >= 3 {
+			return container.Command[len(container.Command)-1]
+		}
+	}
+	return ""
+}
+
+// poll checks condition every PollInterval, until it's done or the timeout
+func poll(ctx context.Context, timeout time.Duration, condition wait.ConditionWithContextFunc) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return wait.PollImmediateUntilWithContext(ctx, PollInterval, condition)
+}
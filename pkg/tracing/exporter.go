@@ -0,0 +1,60 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// newStdoutExporter writes each span as JSON to out
+func newStdoutExporter(out io.Writer) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithWriter(out))
+}
+
+// newOTLPExporter sends spans to the OTLP/HTTP collector at endpoint, a URL
+// (http:// without TLS) or a host:port. Spans are posted to endpoint/v1/traces,
+// as OTEL_EXPORTER_OTLP_ENDPOINT has them.
+func newOTLPExporter(ctx context.Context, endpoint string) (sdktrace.SpanExporter, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("tracing endpoint %q: %w", endpoint, err)
+	}
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(u.Host),
+		otlptracehttp.WithURLPath(path.Join("/", u.Path, "v1", "traces")),
+	}
+	switch u.Scheme {
+	case "http":
+		opts = append(opts, otlptracehttp.WithInsecure())
+	case "https":
+	default:
+		return nil, fmt.Errorf("tracing endpoint %q: the scheme must be http or https", endpoint)
+	}
+	return otlptracehttp.New(ctx, opts...)
+}
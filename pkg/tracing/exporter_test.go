@@ -0,0 +1,164 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+var _ = Describe("Exporters", func() {
+	var (
+		traceID = trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+		spanID  = trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+		start   = time.Unix(1660000000, 123456789)
+	)
+
+	stub := func(name string) tracetest.SpanStub {
+		return tracetest.SpanStub{
+			Name: name,
+			SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled,
+			}),
+			SpanKind:               trace.SpanKindServer,
+			StartTime:              start,
+			EndTime:                start.Add(1500 * time.Millisecond),
+			Attributes:             []attribute.KeyValue{attribute.String("lolcow", "default/moo")},
+			Resource:               resource.NewSchemaless(attribute.String("service.name", "lolcow-operator")),
+			InstrumentationLibrary: instrumentation.Library{Name: tracerName},
+		}
+	}
+
+	It("writes a line of JSON per span to stdout", func() {
+		out := &bytes.Buffer{}
+		exporter, err := newStdoutExporter(out)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exporter.ExportSpans(context.Background(), tracetest.SpanStubs{stub("reconcile"), stub("apply")}.Snapshots())).To(Succeed())
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		Expect(lines).To(HaveLen(2))
+		span := map[string]interface{}{}
+		Expect(json.Unmarshal([]byte(lines[1]), &span)).To(Succeed())
+		Expect(span).To(HaveKeyWithValue("Name", "apply"))
+	})
+
+	Context("OTLP", func() {
+		var (
+			collector *httptest.Server
+			requests  []*http.Request
+			bodies    [][]byte
+			status    int
+		)
+
+		BeforeEach(func() {
+			requests, bodies, status = nil, nil, http.StatusOK
+			collector = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				requests = append(requests, r)
+				bodies = append(bodies, body)
+				w.WriteHeader(status)
+			}))
+		})
+
+		AfterEach(func() {
+			collector.Close()
+		})
+
+		export := func(endpoint string) error {
+			exporter, err := newOTLPExporter(context.Background(), endpoint)
+			Expect(err).NotTo(HaveOccurred())
+			defer exporter.Shutdown(context.Background())
+			failed := stub("reconcile")
+			failed.Status.Code = codes.Error
+			failed.Status.Description = "no moo"
+			return exporter.ExportSpans(context.Background(), tracetest.SpanStubs{failed}.Snapshots())
+		}
+
+		It("posts the protobuf a collector decodes to /v1/traces", func() {
+			Expect(export(collector.URL)).To(Succeed())
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].Method).To(Equal(http.MethodPost))
+			Expect(requests[0].URL.Path).To(Equal("/v1/traces"))
+			Expect(requests[0].Header.Get("Content-Type")).To(Equal("application/x-protobuf"))
+
+			request := &coltracepb.ExportTraceServiceRequest{}
+			Expect(proto.Unmarshal(bodies[0], request)).To(Succeed())
+			Expect(request.ResourceSpans).To(HaveLen(1))
+			Expect(request.ResourceSpans[0].Resource.Attributes[0].Key).To(Equal("service.name"))
+			Expect(request.ResourceSpans[0].Resource.Attributes[0].Value.GetStringValue()).To(Equal("lolcow-operator"))
+			Expect(request.ResourceSpans[0].ScopeSpans).To(HaveLen(1))
+			Expect(request.ResourceSpans[0].ScopeSpans[0].Scope.Name).To(Equal(tracerName))
+			span := request.ResourceSpans[0].ScopeSpans[0].Spans[0]
+			Expect(span.TraceId).To(Equal(traceID[:]))
+			Expect(span.SpanId).To(Equal(spanID[:]))
+			Expect(span.Name).To(Equal("reconcile"))
+			Expect(span.Kind).To(Equal(tracepb.Span_SPAN_KIND_SERVER))
+			Expect(span.StartTimeUnixNano).To(BeEquivalentTo(start.UnixNano()))
+			Expect(span.Status.Code).To(Equal(tracepb.Status_STATUS_CODE_ERROR))
+			Expect(span.Status.Message).To(Equal("no moo"))
+		})
+
+		It("keeps the path of the endpoint, and adds http:// to one without a scheme", func() {
+			Expect(export(strings.TrimPrefix(collector.URL, "http://") + "/otel/")).To(Succeed())
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].URL.Path).To(Equal("/otel/v1/traces"))
+		})
+
+		It("fails when the collector rejects the spans", func() {
+			status = http.StatusBadRequest
+			Expect(export(collector.URL)).To(MatchError(ContainSubstring("400 Bad Request")))
+		})
+
+		It("rejects an endpoint it can't send to", func() {
+			_, err := newOTLPExporter(context.Background(), "grpc://localhost:4317")
+			Expect(err).To(MatchError(`tracing endpoint "grpc://localhost:4317": the scheme must be http or https`))
+		})
+	})
+})
+
+var _ = Describe("Setup", func() {
+	It("is a no-op without an exporter", func() {
+		stop, err := Setup(context.Background(), Options{Exporter: ExporterNone})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stop(context.Background())).To(Succeed())
+	})
+
+	It("rejects an unknown exporter and a bad sample ratio", func() {
+		_, err := Setup(context.Background(), Options{Exporter: "zipkin"})
+		Expect(err).To(MatchError(`unknown tracing exporter "zipkin", choose one of none, stdout or otlp`))
+		_, err = Setup(context.Background(), Options{Exporter: ExporterStdout, SampleRatio: 2})
+		Expect(err).To(MatchError("tracing sample ratio must be between 0 and 1, got 2"))
+	})
+})
@@ -0,0 +1,138 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing is off unless an exporter is chosen. With it off, the global
+// tracer provider is a no-op and spans cost (almost) nothing.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+
+	// DefaultEndpoint is the OTLP/HTTP port of a local collector
+	DefaultEndpoint = "http://localhost:4318"
+
+	serviceName = "lolcow-operator"
+	tracerName  = "vsoch/lolcow-operator"
+)
+
+// Options configure tracing, usually from the command line
+type Options struct {
+
+	// Exporter is none, stdout or otlp
+	Exporter string
+
+	// Endpoint is the OTLP/HTTP collector, e.g., http://otel-collector:4318
+	// Empty uses OTEL_EXPORTER_OTLP_ENDPOINT, and then DefaultEndpoint
+	Endpoint string
+
+	// SampleRatio is the fraction of reconciles traced (1 is all of them)
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider. The returned function
+// flushes and stops it, and should be called on the way out.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+
+	switch opts.Exporter {
+	case "", ExporterNone:
+		return noop, nil
+	case ExporterStdout, ExporterOTLP:
+	default:
+		return noop, fmt.Errorf("unknown tracing exporter %q, choose one of %s, %s or %s",
+			opts.Exporter, ExporterNone, ExporterStdout, ExporterOTLP)
+	}
+	if opts.SampleRatio < 0 || opts.SampleRatio > 1 {
+		return noop, fmt.Errorf("tracing sample ratio must be between 0 and 1, got %v", opts.SampleRatio)
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	if opts.Exporter == ExporterStdout {
+		exporter, err = newStdoutExporter(os.Stdout)
+	} else {
+		endpoint := opts.Endpoint
+		if endpoint == "" {
+			endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		}
+		if endpoint == "" {
+			endpoint = DefaultEndpoint
+		}
+		exporter, err = newOTLPExporter(ctx, endpoint)
+	}
+	if err != nil {
+		return noop, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithAttributes(semconv.ServiceNameKey.String(serviceName)),
+	)
+	if err != nil {
+		return noop, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// Start starts a span from the global tracer provider
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends a span, marking it failed if there was an error
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// WithTraceID adds the trace and span ids of the span in ctx to the
+// logger, so logs can be matched to their trace. Without one (e.g., tracing
+// is off) the logger is returned as is.
+func WithTraceID(ctx context.Context, log logr.Logger) logr.Logger {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return log
+	}
+	return log.WithValues("trace_id", spanContext.TraceID().String(), "span_id", spanContext.SpanID().String())
+}
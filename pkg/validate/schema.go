@@ -0,0 +1,184 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema/cel"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema/defaulting"
+	apiservervalidation "k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	openapivalidate "k8s.io/kube-openapi/pkg/validation/validate"
+	"sigs.k8s.io/yaml"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+	"vsoch/lolcow-operator/config/crd"
+)
+
+// Schema checks lolcows with the API server's own validation of a custom
+// resource: the OpenAPI schema, and then the x-kubernetes-validations (CEL) rules
+type Schema struct {
+	structural *structuralschema.Structural
+	validator  *openapivalidate.SchemaValidator
+	rules      *cel.Validator
+}
+
+// LolcowSchema is the schema of the served Lolcow version, from the CRD
+// that ships with the operator
+func LolcowSchema() (*Schema, error) {
+	definition := &apiextensionsv1.CustomResourceDefinition{}
+	if err := yaml.Unmarshal(crd.Lolcows, definition); err != nil {
+		return nil, fmt.Errorf("reading the Lolcow CRD: %w", err)
+	}
+	for _, version := range definition.Spec.Versions {
+		if version.Name != api.GroupVersion.Version || version.Schema == nil {
+			continue
+		}
+		internal := &apiextensions.JSONSchemaProps{}
+		err := apiextensionsv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(version.Schema.OpenAPIV3Schema, internal, nil)
+		if err != nil {
+			return nil, err
+		}
+		structural, err := structuralschema.NewStructural(internal)
+		if err != nil {
+			return nil, err
+		}
+		validator, _, err := apiservervalidation.NewSchemaValidator(&apiextensions.CustomResourceValidation{OpenAPIV3Schema: internal})
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{structural: structural, validator: validator, rules: cel.NewValidator(structural, cel.PerCallLimit)}, nil
+	}
+	return nil, fmt.Errorf("the Lolcow CRD has no %s schema", api.GroupVersion.Version)
+}
+
+// Default fills in the schema defaults of missing fields
+func (s *Schema) Default(object map[string]interface{}) {
+	defaulting.Default(object, s.structural)
+}
+
+// Validate checks an object against the schema, and then its CEL rules
+// Like the API server, the rules are skipped if the schema found a wrong
+// type, a missing field or a value that's too long, as they can't be
+// evaluated on those: they show up once those are fixed.
+func (s *Schema) Validate(ctx context.Context, object map[string]interface{}) field.ErrorList {
+	errs := apiservervalidation.ValidateCustomResource(nil, object, s.validator)
+	for _, err := range errs {
+		switch err.Type {
+		case field.ErrorTypeRequired, field.ErrorTypeTooLong, field.ErrorTypeTooMany, field.ErrorTypeTypeInvalid:
+			return errs
+		}
+	}
+	ruleErrs, _ := s.rules.Validate(ctx, nil, s.structural, object, nil, cel.RuntimeCELCostBudget)
+	return append(errs, ruleErrs...)
+}
+
+// dropMistyped removes the values of the wrong type, which the schema
+// reported, so the rest still decodes into a Lolcow
+func dropMistyped(value interface{}, s *structuralschema.Structural) {
+	if s == nil {
+		return
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for name, item := range value {
+			property, ok := s.Properties[name]
+			if !ok && s.AdditionalProperties != nil && s.AdditionalProperties.Structural != nil {
+				property, ok = *s.AdditionalProperties.Structural, true
+			}
+			if !ok {
+				continue
+			}
+			if mistyped(item, &property) {
+				delete(value, name)
+				continue
+			}
+			dropMistyped(item, &property)
+		}
+	case []interface{}:
+		for i, item := range value {
+			if mistyped(item, s.Items) {
+				value[i] = nil
+				continue
+			}
+			dropMistyped(item, s.Items)
+		}
+	}
+}
+
+// mistyped is true if a value isn't of its schema's type, or is a null it can't be
+func mistyped(value interface{}, s *structuralschema.Structural) bool {
+	if s == nil {
+		return false
+	}
+	if value == nil {
+		return !s.Nullable && s.Type != ""
+	}
+	if s.XIntOrString {
+		_, ok := value.(string)
+		return !ok && !isInteger(value)
+	}
+	return s.Type != "" && !hasType(value, s.Type)
+}
+
+// hasType is true if a json value is of an OpenAPI type
+func hasType(value interface{}, openAPIType string) bool {
+	switch openAPIType {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		return isInteger(value)
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	}
+	return true
+}
+
+// isInteger is true for whole numbers, which json decodes as float64
+func isInteger(value interface{}) bool {
+	number, ok := toFloat(value)
+	return ok && number == math.Trunc(number)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case int64:
+		return float64(value), true
+	case int:
+		return float64(value), true
+	}
+	return 0, false
+}
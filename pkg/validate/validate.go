@@ -0,0 +1,210 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package validate is lolcow-operator validate, which checks lolcow
+// manifests without a cluster: against the CRD schema, like the API server,
+// and with the same defaulting and validation as the webhook.
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema/pruning"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+
+	api "vsoch/lolcow-operator/api/lolcow/v1alpha1"
+	"vsoch/lolcow-operator/pkg/manifest"
+)
+
+// ErrInvalid is returned when any lolcow is invalid
+var ErrInvalid = errors.New("some lolcows are invalid")
+
+// Problem is a field of a lolcow that's invalid, and where it is
+type Problem struct {
+	Document manifest.Document
+	Line     int
+	Error    *field.Error
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s", p.Document.File, p.Line, p.Error)
+}
+
+// Options for validate
+type Options struct {
+	// Files with lolcows, - is stdin. Other kinds are skipped.
+	Files []string
+
+	In     io.Reader
+	Out    io.Writer
+	ErrOut io.Writer
+}
+
+// Run parses the validate flags in args, and validates
+func Run(ctx context.Context, args []string, in io.Reader, out, errOut io.Writer) error {
+	o := &Options{In: in, Out: out, ErrOut: errOut}
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	fs.Usage = func() {
+		fmt.Fprintln(errOut, "Usage: lolcow-operator validate -f lolcow.yaml [-f more.yaml]")
+		fmt.Fprintln(errOut, "\nCheck lolcows against the CRD schema and the webhook, without a cluster.")
+		fmt.Fprint(errOut, "Every problem is printed with its file and line, and it exits 1 if there are any.\n\n")
+		fs.PrintDefaults()
+	}
+	files := fileList{}
+	fs.Var(&files, "f", "A file with lolcows, - for stdin. Can be given more than once.")
+	fs.Var(&files, "filename", "The same as -f.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	o.Files = files
+	if len(o.Files) == 0 {
+		fs.Usage()
+		return fmt.Errorf("validate needs at least one -f")
+	}
+	return o.Validate(ctx)
+}
+
+// Validate prints the problems of every lolcow in the files, and returns
+// ErrInvalid if there are any
+func (o *Options) Validate(ctx context.Context) error {
+	documents, err := manifest.Read(o.Files, o.In)
+	if err != nil {
+		return err
+	}
+	schema, err := LolcowSchema()
+	if err != nil {
+		return err
+	}
+	lolcows := 0
+	problems := 0
+	for _, document := range documents {
+		if document.TypeMeta.Kind != "Lolcow" {
+			continue
+		}
+		lolcows++
+		found, err := Document(ctx, document, schema)
+		if err != nil {
+			return err
+		}
+		for _, problem := range found {
+			fmt.Fprintln(o.Out, problem)
+		}
+		problems += len(found)
+	}
+	if lolcows == 0 {
+		return fmt.Errorf("no lolcows in %s", strings.Join(o.Files, ", "))
+	}
+	if problems > 0 {
+		fmt.Fprintf(o.ErrOut, "❌️ %d problem(s) in %d lolcow(s)\n", problems, lolcows)
+		return ErrInvalid
+	}
+	fmt.Fprintf(o.ErrOut, "✅️ %d lolcow(s) are valid\n", lolcows)
+	return nil
+}
+
+// Document checks one lolcow the way the API server would: unknown fields
+// (which it would drop), schema defaults, and the schema and its CEL rules
+// first, and then the webhook's defaulting and validation on what's left.
+func Document(ctx context.Context, document manifest.Document, schema *Schema) ([]Problem, error) {
+	if document.TypeMeta.APIVersion != api.GroupVersion.String() {
+		return nil, fmt.Errorf("%s: unknown lolcow apiVersion %q, expected %s", document, document.TypeMeta.APIVersion, api.GroupVersion)
+	}
+	// Whole numbers are int64, as the API server decodes them for CEL
+	raw, err := yaml.YAMLToJSON(document.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", document, err)
+	}
+	object := map[string]interface{}{}
+	if err := utiljson.Unmarshal(raw, &object); err != nil {
+		return nil, fmt.Errorf("%s: %w", document, err)
+	}
+
+	errs := field.ErrorList{}
+	for _, path := range pruning.PruneWithOptions(object, schema.structural, true, pruning.PruneOptions{ReturnPruned: true}) {
+		errs = append(errs, field.Forbidden(field.NewPath(path), "unknown field"))
+	}
+	schema.Default(object)
+	errs = append(errs, validateMetadata(object)...)
+	errs = append(errs, schema.Validate(ctx, object)...)
+
+	// The API server would stop at the schema, but we report everything
+	// the webhook finds too, unless the field is already wrong
+	dropMistyped(object, schema.structural)
+	raw, err = json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	lolcow := &api.Lolcow{}
+	if err := json.Unmarshal(raw, lolcow); err == nil {
+		reported := map[string]bool{}
+		for _, err := range errs {
+			reported[err.Field] = true
+		}
+		lolcow.Default()
+		for _, err := range api.ValidateLolcow(lolcow) {
+			if !reported[err.Field] {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	problems := []Problem{}
+	for _, err := range errs {
+		problems = append(problems, Problem{Document: document, Line: document.LineOf(err.Field), Error: err})
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Line < problems[j].Line
+	})
+	return problems, nil
+}
+
+// validateMetadata checks the name, which the schema leaves to the API server
+func validateMetadata(object map[string]interface{}) field.ErrorList {
+	path := field.NewPath("metadata", "name")
+	metadata, _ := object["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	if name == "" {
+		return field.ErrorList{field.Required(path, "")}
+	}
+	errs := field.ErrorList{}
+	for _, message := range validation.IsDNS1123Subdomain(name) {
+		errs = append(errs, field.Invalid(path, name, message))
+	}
+	return errs
+}
+
+// fileList is a flag that can be given more than once
+type fileList []string
+
+func (f *fileList) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *fileList) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
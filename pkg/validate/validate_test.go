@@ -0,0 +1,162 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"vsoch/lolcow-operator/pkg/manifest"
+)
+
+const lolcow = `apiVersion: my.domain/v1alpha1
+kind: Lolcow
+metadata:
+  name: hello-world
+spec:
+  port: 30080
+  greeting: Moo
+  serviceType: NodePort
+`
+
+var _ = Describe("lolcow-operator validate", func() {
+	var (
+		dir    string
+		out    *bytes.Buffer
+		errOut *bytes.Buffer
+	)
+
+	// write puts content in a file in dir, and returns its path
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+		return path
+	}
+
+	run := func(stdin string, args ...string) error {
+		out.Reset()
+		errOut.Reset()
+		return Run(context.Background(), args, strings.NewReader(stdin), out, errOut)
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "validate")
+		Expect(err).NotTo(HaveOccurred())
+		out = &bytes.Buffer{}
+		errOut = &bytes.Buffer{}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("accepts a valid lolcow", func() {
+		Expect(run("", "-f", write("lolcow.yaml", lolcow))).To(Succeed())
+		Expect(out.String()).To(BeEmpty())
+		Expect(errOut.String()).To(ContainSubstring("1 lolcow(s) are valid"))
+	})
+
+	It("reports schema problems with their line", func() {
+		path := write("lolcow.yaml", lolcow+"  colour: brown\n  servicePort: \"http\"\n  containerPort: 0\n")
+		Expect(run("", "-f", path)).To(MatchError(ErrInvalid))
+		Expect(out.String()).To(ContainSubstring(path + ":9: spec.colour: Forbidden: unknown field"))
+		Expect(out.String()).To(ContainSubstring(path + `:10: spec.servicePort: Invalid value: "string": spec.servicePort in body must be of type integer`))
+		Expect(out.String()).To(ContainSubstring(path + ":11: spec.containerPort: Invalid value: 0: spec.containerPort in body should be greater than or equal to 1"))
+	})
+
+	It("reports a missing required field at its parent", func() {
+		content := strings.Replace(lolcow, "  greeting: Moo\n", "  greetingFrom:\n    name: greetings\n", 1)
+		path := write("lolcow.yaml", content)
+		Expect(run("", "-f", path)).To(MatchError(ErrInvalid))
+		Expect(out.String()).To(ContainSubstring(path + ":7: spec.greetingFrom.key: Required value"))
+	})
+
+	It("reports what the webhook rejects, too", func() {
+		content := strings.Replace(lolcow, "  name: hello-world\n", "  name: hello-world\n  annotations:\n    lolcow.my.domain/paused: maybe\n", 1)
+		content = strings.Replace(content, "port: 30080", "port: 70000", 1) + "  image: my cow\n"
+		path := write("lolcow.yaml", content)
+		Expect(run("", "-f", path)).To(MatchError(ErrInvalid))
+		Expect(out.String()).To(ContainSubstring(path + ":6: metadata.annotations[lolcow.my.domain/paused]"))
+		Expect(out.String()).To(ContainSubstring(path + ":8: spec.port: Invalid value: 70000"))
+		Expect(out.String()).To(ContainSubstring(path + ":11: spec.image"))
+	})
+
+	It("reports what the CRD's CEL rules reject", func() {
+		content := strings.Replace(lolcow, "NodePort", "ClusterIP", 1) + "  greetingFrom:\n    name: greetings\n    key: monday\n"
+		path := write("lolcow.yaml", content)
+		Expect(run("", "-f", path)).To(MatchError(ErrInvalid))
+		Expect(out.String()).To(ContainSubstring(path + `:5: spec: Invalid value: "object": port is only allowed for NodePort or LoadBalancer services`))
+		Expect(out.String()).To(ContainSubstring(path + `:5: spec: Invalid value: "object": greeting and greetingFrom are mutually exclusive`))
+	})
+
+	It("accepts a port without a serviceType, like lolcows from before serviceType", func() {
+		Expect(run("", "-f", write("lolcow.yaml", strings.Replace(lolcow, "  serviceType: NodePort\n", "", 1)))).To(Succeed())
+	})
+
+	It("reports a port outside the node port range", func() {
+		path := write("lolcow.yaml", strings.Replace(lolcow, "port: 30080", "port: 8080", 1))
+		Expect(run("", "-f", path)).To(MatchError(ErrInvalid))
+		Expect(out.String()).To(ContainSubstring(path + ":6: spec.port: Invalid value: 8080: spec.port in body should be greater than or equal to 30000"))
+	})
+
+	It("runs the CEL rules of nested fields", func() {
+		path := write("lolcow.yaml", lolcow+"  networkPolicy:\n    allowedFrom:\n      - except: [10.0.1.0/24]\n")
+		Expect(run("", "-f", path)).To(MatchError(ErrInvalid))
+		Expect(out.String()).To(ContainSubstring(path + ":11: spec.networkPolicy.allowedFrom[0]: Invalid value: \"object\": set a cidr"))
+		Expect(out.String()).To(ContainSubstring(path + ":11: spec.networkPolicy.allowedFrom[0]: Invalid value: \"object\": except may only be set with a cidr"))
+	})
+
+	It("reports a long greeting", func() {
+		path := write("lolcow.yaml", strings.Replace(lolcow, "greeting: Moo", "greeting: "+strings.Repeat("Moo", 100), 1))
+		Expect(run("", "-f", path)).To(MatchError(ErrInvalid))
+		Expect(out.String()).To(ContainSubstring(path + ":7: spec.greeting: Too long"))
+	})
+
+	It("checks every lolcow, from stdin too", func() {
+		stdin := lolcow + "---\n" + strings.Replace(lolcow, "hello-world", "Hello_World", 1)
+		Expect(run(stdin, "-f", "-")).To(MatchError(ErrInvalid))
+		Expect(out.String()).To(ContainSubstring(`<stdin>:13: metadata.name: Invalid value: "Hello_World"`))
+		Expect(errOut.String()).To(ContainSubstring("1 problem(s) in 2 lolcow(s)"))
+	})
+
+	It("skips other kinds, but needs a lolcow", func() {
+		path := write("other.yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: moo\n")
+		Expect(run("", "-f", path)).To(MatchError(ContainSubstring("no lolcows in")))
+	})
+
+	It("needs a file", func() {
+		Expect(run("")).To(MatchError(ContainSubstring("at least one -f")))
+	})
+})
+
+var _ = Describe("Document", func() {
+	It("rejects another version", func() {
+		schema, err := LolcowSchema()
+		Expect(err).NotTo(HaveOccurred())
+		documents, err := manifest.Parse("lolcow.yaml", []byte(strings.Replace(lolcow, "v1alpha1", "v1", 1)))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = Document(context.Background(), documents[0], schema)
+		Expect(err).To(MatchError(ContainSubstring(`unknown lolcow apiVersion "my.domain/v1"`)))
+	})
+})